@@ -8,14 +8,18 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/falling-sky/source/pkg/ipv6test"
-	"github.com/oschwald/geoip2-golang"
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
+	"github.com/falling-sky/source/pkg/ipv6test/ipmeta"
 )
 
 type server struct {
@@ -25,7 +29,7 @@ type server struct {
 		sync.RWMutex
 		data map[string]ipv6test.RunResult
 	}
-	asnDB *geoip2.Reader
+	ipmeta ipmeta.Provider
 }
 
 func main() {
@@ -37,7 +41,21 @@ func main() {
 	timeoutFlag := flag.Duration("timeout", envDuration("TESTIPV6_TIMEOUT", 15*time.Second), "per-test timeout")
 	slowFlag := flag.Duration("slow", envDuration("TESTIPV6_SLOW", 5*time.Second), "slow threshold")
 	packetFlag := flag.Int("packet-size", envInt("TESTIPV6_PACKET_SIZE", 1600), "packet size for MTU-style tests")
-	asnFlag := flag.String("asn-db", env("TESTIPV6_ASN_DB", ""), "path to GeoLite2-ASN.mmdb for ASN lookups")
+	asnFlag := flag.String("asn-db", env("TESTIPV6_ASN_DB", ""), "path to GeoLite2-ASN.mmdb for the maxmind ipmeta backend")
+	countryDBFlag := flag.String("country-db", env("TESTIPV6_COUNTRY_DB", ""), "path to GeoLite2-Country.mmdb for the maxmind ipmeta backend (optional; enables country fields)")
+	ispDBFlag := flag.String("isp-db", env("TESTIPV6_ISP_DB", ""), "path to a GeoIP2-ISP.mmdb for the maxmind ipmeta backend (optional; overrides the ASN org with the ISP record's)")
+	ipmetaFlag := flag.String("ipmeta", env("TESTIPV6_IPMETA", ""), "comma separated IP-metadata backends, e.g. maxmind,rdap,bgp (default: maxmind if -asn-db is set, else none)")
+	rdapBaseFlag := flag.String("ipmeta-rdap-base", env("TESTIPV6_IPMETA_RDAP_BASE", ""), "RDAP bootstrap base URL for the rdap ipmeta backend")
+	irrHostFlag := flag.String("ipmeta-irr-host", env("TESTIPV6_IPMETA_IRR_HOST", ""), "host:port of a whois server for the irr ipmeta backend")
+	bgpRIBFlag := flag.String("ipmeta-bgp-rib", env("TESTIPV6_IPMETA_BGP_RIB", ""), "path to a local MRT TABLE_DUMPV2 RIB dump for the bgp ipmeta backend")
+	addrPolicyFlag := flag.String("address-policy", env("TESTIPV6_ADDRESS_POLICY", ""), "default address-family dial policy for HTTP tests: ipv4_only, ipv6_only, ipv4_prefer, ipv6_prefer, happy_eyeballs (default: system default, untouched)")
+	preferredFamFlag := flag.String("preferred-family", env("TESTIPV6_PREFERRED_FAMILY", ""), "family that gets the head start under happy_eyeballs (ipv4 or ipv6; default ipv6)")
+	headStartFlag := flag.Duration("happy-eyeballs-headstart", envDuration("TESTIPV6_HAPPY_EYEBALLS_HEADSTART", 0), "how long the preferred family gets before the other is raced alongside it (default 250ms)")
+	extraResFlag := flag.String("extra-resolvers", env("TESTIPV6_EXTRA_RESOLVERS", ""), "comma separated resolver URIs for resolver_aaaa/resolver_doh6, e.g. udp://1.1.1.1:53,tls://dns.google:853,https://cloudflare-dns.com/dns-query")
+	stunV4Flag := flag.String("stun-servers-v4", env("TESTIPV6_STUN_SERVERS_V4", ""), "comma separated host:port STUN servers for netcheck_v4")
+	stunV6Flag := flag.String("stun-servers-v6", env("TESTIPV6_STUN_SERVERS_V6", ""), "comma separated host:port STUN servers for netcheck_v6")
+	maxParallelFlag := flag.Int("max-parallel", envInt("TESTIPV6_MAX_PARALLEL", 0), "max tests run concurrently per request (default 4)")
+	maxConnsFlag := flag.Int("max-conns-per-host", envInt("TESTIPV6_MAX_CONNS_PER_HOST", 0), "max concurrent connections to a single test host (default 6)")
 	flag.Parse()
 
 	addr := *addrFlag
@@ -47,6 +65,10 @@ func main() {
 	slow := *slowFlag
 	packetSize := *packetFlag
 	asnPath := *asnFlag
+	ipmetaSpec := *ipmetaFlag
+	if ipmetaSpec == "" && asnPath != "" {
+		ipmetaSpec = "maxmind"
+	}
 
 	opts := ipv6test.DefaultOptions()
 	opts.Domain = domain
@@ -54,21 +76,49 @@ func main() {
 	opts.Timeout = timeout
 	opts.SlowThreshold = slow
 	opts.PacketSize = packetSize
+	if *addrPolicyFlag != "" {
+		opts.AddressPolicy = dialpolicy.Policy(*addrPolicyFlag)
+	}
+	opts.PreferredFamily = *preferredFamFlag
+	opts.HappyEyeballsHeadStart = *headStartFlag
+	if *extraResFlag != "" {
+		for _, spec := range strings.Split(*extraResFlag, ",") {
+			spec = strings.TrimSpace(spec)
+			if spec != "" {
+				opts.ExtraResolvers = append(opts.ExtraResolvers, spec)
+			}
+		}
+	}
+	opts.STUNServersV4 = splitCSV(*stunV4Flag)
+	opts.STUNServersV6 = splitCSV(*stunV6Flag)
+	opts.MaxParallel = *maxParallelFlag
+	opts.MaxConnsPerHost = *maxConnsFlag
+
+	var provider ipmeta.Provider
+	if ipmetaSpec != "" {
+		p, err := ipmeta.New(ipmetaSpec, ipmeta.Config{
+			MaxMindDBPath:        asnPath,
+			MaxMindCountryDBPath: *countryDBFlag,
+			MaxMindISPDBPath:     *ispDBFlag,
+			RDAPBaseURL:          *rdapBaseFlag,
+			IRRHost:              *irrHostFlag,
+			BGPRIBPath:           *bgpRIBFlag,
+		})
+		if err != nil {
+			log.Printf("ipmeta init failed (%s): %v", ipmetaSpec, err)
+		} else {
+			provider = p
+			log.Printf("ipmeta backend(s) loaded: %s", ipmetaSpec)
+		}
+	}
+	opts.IPMeta = provider
 
 	s := &server{
 		opts:   opts,
 		runner: ipv6test.NewRunner(opts),
+		ipmeta: provider,
 	}
 	s.store.data = make(map[string]ipv6test.RunResult)
-	if asnPath != "" {
-		db, err := geoip2.Open(asnPath)
-		if err != nil {
-			log.Printf("asn db load failed (%s): %v", asnPath, err)
-		} else {
-			s.asnDB = db
-			log.Printf("asn db loaded: %s", asnPath)
-		}
-	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", s.handleHealth)
@@ -78,12 +128,30 @@ func main() {
 	mux.HandleFunc("/ip/", s.handleIP)
 	mux.HandleFunc("/ip", s.handleIP)
 
+	go s.reloadGeoOnSIGHUP()
+
 	log.Printf("testipv6-server listening on %s (domain=%s)", addr, domain)
 	if err := http.ListenAndServe(addr, mux); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
 
+// reloadGeoOnSIGHUP reopens the configured ipmeta mmdb files (see
+// Runner.ReloadGeo) each time the process receives SIGHUP, so a
+// cron-refreshed GeoLite2 database takes effect without a restart. It
+// never returns; run it in its own goroutine.
+func (s *server) reloadGeoOnSIGHUP() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := s.runner.ReloadGeo(); err != nil {
+			log.Printf("geo reload failed: %v", err)
+		} else {
+			log.Printf("geo databases reloaded")
+		}
+	}
+}
+
 func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -104,6 +172,7 @@ type runRequest struct {
 	TimeoutMs       int64               `json:"timeoutMs"`
 	SlowThresholdMs int64               `json:"slowThresholdMs"`
 	PacketSizeBytes int                 `json:"packetSizeBytes"`
+	AddressPolicy   string              `json:"addressPolicy"`
 }
 
 func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
@@ -134,6 +203,9 @@ func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
 	if req.SlowThresholdMs > 0 {
 		runReq.SlowThreshold = time.Duration(req.SlowThresholdMs) * time.Millisecond
 	}
+	if req.AddressPolicy != "" {
+		runReq.AddressPolicy = dialpolicy.Policy(req.AddressPolicy)
+	}
 
 	result, err := s.runner.Run(context.Background(), runReq)
 	if err != nil {
@@ -194,11 +266,14 @@ func (s *server) handleIP(w http.ResponseWriter, r *http.Request) {
 		IP:   ipStr,
 		Type: ipType,
 	}
-	if s.asnDB != nil && ipStr != "" {
-		if addr := net.ParseIP(ipStr); addr != nil {
-			if rec, err := s.asnDB.ASN(addr); err == nil {
-				ipObs.ASN = int(rec.AutonomousSystemNumber)
-				ipObs.ASNName = rec.AutonomousSystemOrganization
+	if s.ipmeta != nil && ipStr != "" {
+		if addr, err := netip.ParseAddr(ipStr); err == nil {
+			if meta, err := s.ipmeta.Lookup(r.Context(), addr); err == nil {
+				ipObs.ASN = meta.ASN
+				ipObs.ASNName = meta.ASNName
+				if ipObs.ASNName == "" {
+					ipObs.ASNName = meta.Org
+				}
 			}
 		}
 	}
@@ -237,6 +312,17 @@ func envDuration(key string, def time.Duration) time.Duration {
 	return def
 }
 
+func splitCSV(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func envInt(key string, def int) int {
 	if val := os.Getenv(key); val != "" {
 		if n, err := strconv.Atoi(val); err == nil {
@@ -5,23 +5,43 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/falling-sky/source/pkg/ipv6test"
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
+	"github.com/falling-sky/source/pkg/ipv6test/ipmeta"
 )
 
 func main() {
 	var (
-		domain   = flag.String("domain", "toany.net", "Base domain for test endpoints")
-		lookup   = flag.String("lookup-domain", "", "Lookup domain for ASN endpoints (default: same as domain)")
-		timeout  = flag.Duration("timeout", 15*time.Second, "Per-test timeout")
-		slow     = flag.Duration("slow", 5*time.Second, "Slow threshold")
-		packet   = flag.Int("packet-size", 1600, "Packet size for MTU-style tests")
-		testsCSV = flag.String("tests", "", "Comma separated test names (default: all)")
-		jsonOut  = flag.Bool("json", false, "Output JSON instead of human readable text")
-		showErrs = flag.Bool("show-errors", false, "Show error details (truncated); default hides error strings for cleaner output")
+		domain        = flag.String("domain", "toany.net", "Base domain for test endpoints")
+		lookup        = flag.String("lookup-domain", "", "Lookup domain for ASN endpoints (default: same as domain)")
+		timeout       = flag.Duration("timeout", 15*time.Second, "Per-test timeout")
+		slow          = flag.Duration("slow", 5*time.Second, "Slow threshold")
+		packet        = flag.Int("packet-size", 1600, "Packet size for MTU-style tests")
+		testsCSV      = flag.String("tests", "", "Comma separated test names (default: all)")
+		resolversCSV  = flag.String("resolvers", "", "Comma separated DNS resolver ip:port to use for dns_* tests (default: system resolver(s))")
+		jsonOut       = flag.Bool("json", false, "Output JSON instead of human readable text")
+		showErrs      = flag.Bool("show-errors", false, "Show error details (truncated); default hides error strings for cleaner output")
+		ipmetaSpec    = flag.String("ipmeta", os.Getenv("TESTIPV6_IPMETA"), "Comma separated IP-metadata backends to enrich observed addresses with, e.g. maxmind,rdap,bgp (default: none)")
+		maxmindDB     = flag.String("ipmeta-maxmind-db", "", "Path to GeoLite2-ASN.mmdb for the maxmind ipmeta backend")
+		maxmindCtryDB = flag.String("ipmeta-maxmind-country-db", "", "Path to GeoLite2-Country.mmdb for the maxmind ipmeta backend (optional; enables country fields)")
+		maxmindISPDB  = flag.String("ipmeta-maxmind-isp-db", "", "Path to a GeoIP2-ISP.mmdb for the maxmind ipmeta backend (optional; overrides the ASN org with the ISP record's)")
+		rdapBaseURL   = flag.String("ipmeta-rdap-base", "", "RDAP bootstrap base URL for the rdap ipmeta backend (default: https://rdap.org)")
+		irrHost       = flag.String("ipmeta-irr-host", "", "host:port of a whois server for the irr ipmeta backend (default: whois.radb.net:43)")
+		bgpRIBPath    = flag.String("ipmeta-bgp-rib", "", "Path to a local MRT TABLE_DUMPV2 RIB dump for the bgp ipmeta backend")
+		addrPolicy    = flag.String("address-policy", "", "Address-family dial policy for HTTP tests: ipv4_only, ipv6_only, ipv4_prefer, ipv6_prefer, happy_eyeballs (default: system default, untouched)")
+		preferredFam  = flag.String("preferred-family", "", "Family that gets the head start under happy_eyeballs (ipv4 or ipv6; default ipv6)")
+		headStart     = flag.Duration("happy-eyeballs-headstart", 0, "How long the preferred family gets before the other is raced alongside it (default 250ms)")
+		extraResCSV   = flag.String("extra-resolvers", "", "Comma separated resolver URIs for resolver_aaaa/resolver_doh6, e.g. udp://1.1.1.1:53,tls://dns.google:853,https://cloudflare-dns.com/dns-query")
+		stunV4CSV     = flag.String("stun-servers-v4", "", "Comma separated host:port STUN servers for netcheck_v4")
+		stunV6CSV     = flag.String("stun-servers-v6", "", "Comma separated host:port STUN servers for netcheck_v6")
+		maxParallel   = flag.Int("max-parallel", 0, "Max tests run concurrently (default 4)")
+		maxConns      = flag.Int("max-conns-per-host", 0, "Max concurrent connections to a single test host (default 6)")
+		progress      = flag.Bool("progress", false, "Print live per-test progress to stderr as tests complete, via RunStream")
 	)
 	flag.Parse()
 
@@ -35,6 +55,54 @@ func main() {
 	opts.Timeout = *timeout
 	opts.SlowThreshold = *slow
 	opts.PacketSize = *packet
+	if *resolversCSV != "" {
+		for _, r := range strings.Split(*resolversCSV, ",") {
+			r = strings.TrimSpace(r)
+			if r == "" {
+				continue
+			}
+			addr, err := netip.ParseAddrPort(r)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid resolver %q: %v\n", r, err)
+				os.Exit(1)
+			}
+			opts.Resolvers = append(opts.Resolvers, addr)
+		}
+	}
+
+	if *addrPolicy != "" {
+		opts.AddressPolicy = dialpolicy.Policy(*addrPolicy)
+	}
+	opts.PreferredFamily = *preferredFam
+	opts.HappyEyeballsHeadStart = *headStart
+	if *extraResCSV != "" {
+		for _, spec := range strings.Split(*extraResCSV, ",") {
+			spec = strings.TrimSpace(spec)
+			if spec != "" {
+				opts.ExtraResolvers = append(opts.ExtraResolvers, spec)
+			}
+		}
+	}
+	opts.STUNServersV4 = splitCSV(*stunV4CSV)
+	opts.STUNServersV6 = splitCSV(*stunV6CSV)
+	opts.MaxParallel = *maxParallel
+	opts.MaxConnsPerHost = *maxConns
+
+	if *ipmetaSpec != "" {
+		provider, err := ipmeta.New(*ipmetaSpec, ipmeta.Config{
+			MaxMindDBPath:        *maxmindDB,
+			MaxMindCountryDBPath: *maxmindCtryDB,
+			MaxMindISPDBPath:     *maxmindISPDB,
+			RDAPBaseURL:          *rdapBaseURL,
+			IRRHost:              *irrHost,
+			BGPRIBPath:           *bgpRIBPath,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ipmeta: %v\n", err)
+			os.Exit(1)
+		}
+		opts.IPMeta = provider
+	}
 
 	runner := ipv6test.NewRunner(opts)
 
@@ -49,15 +117,23 @@ func main() {
 		}
 	}
 
-	result, err := runner.Run(context.Background(), ipv6test.RunRequest{
+	req := ipv6test.RunRequest{
 		Tests:           tests,
 		Timeout:         opts.Timeout,
 		SlowThreshold:   opts.SlowThreshold,
 		PacketSizeBytes: opts.PacketSize,
-	})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "run failed: %v\n", err)
-		os.Exit(1)
+	}
+
+	var result ipv6test.RunResult
+	if *progress {
+		result = runWithProgress(runner, req)
+	} else {
+		var err error
+		result, err = runner.Run(context.Background(), req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run failed: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	if *jsonOut {
@@ -85,6 +161,18 @@ func main() {
 	printTokens(analysis.Tokens)
 }
 
+// runWithProgress drives RunStream directly instead of Run, printing each
+// test's phase transitions to stderr as they arrive, so -progress gives a
+// live view of what the concurrent scheduler is doing instead of just a
+// final summary.
+func runWithProgress(runner *ipv6test.Runner, req ipv6test.RunRequest) ipv6test.RunResult {
+	events, results := runner.RunStream(context.Background(), req)
+	for ev := range events {
+		fmt.Fprintf(os.Stderr, "[%s] %-14s %s\n", ev.Timestamp.Format("15:04:05.000"), ev.TestName, ev.Phase)
+	}
+	return <-results
+}
+
 func printScores(a ipv6test.Analysis) {
 	s4 := "n/a"
 	if a.ScoreTransition >= 0 {
@@ -130,6 +218,17 @@ func markerForColor(color string) string {
 	}
 }
 
+func splitCSV(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func truncateErr(s string) string {
 	const max = 200
 	if len(s) <= max {
@@ -0,0 +1,190 @@
+// Package addrselect implements RFC 6724 destination address selection --
+// the same rules Go's net package applies internally (see net/addrselect.go)
+// when a dual-stack dial has more than one candidate address pair to choose
+// from. Given the client's observed IPv4/IPv6 source addresses and a test
+// endpoint's resolved A/AAAA records, it answers the real question behind
+// "does IPv6 work": which family will the client's OS actually pick.
+package addrselect
+
+import "net/netip"
+
+// scope mirrors the RFC 4291 multicast scope values, reused by RFC 6724 for
+// unicast classification (anything not loopback/link-local is "global").
+type scope int
+
+const (
+	scopeLinkLocal scope = 0x2
+	scopeGlobal    scope = 0xe
+)
+
+// policyEntry is one row of the RFC 6724 default policy table.
+type policyEntry struct {
+	prefix     netip.Prefix
+	precedence int
+	label      int
+}
+
+// defaultPolicyTable is the table from RFC 6724 section 2.1, sorted by
+// descending prefix length (most specific first, ::/0 last) exactly like
+// Go's net/addrselect.go requires -- classify below returns on first
+// match, so a looser entry listed earlier would shadow every more
+// specific entry after it.
+var defaultPolicyTable = []policyEntry{
+	{mustPrefix("::1/128"), 50, 0},
+	{mustPrefix("::ffff:0:0/96"), 35, 4},
+	{mustPrefix("::/96"), 1, 3},
+	{mustPrefix("2001::/32"), 5, 5},
+	{mustPrefix("2002::/16"), 30, 2},
+	{mustPrefix("3ffe::/16"), 1, 12},
+	{mustPrefix("fec0::/10"), 1, 11},
+	{mustPrefix("fc00::/7"), 3, 13},
+	{mustPrefix("::/0"), 40, 1},
+}
+
+func mustPrefix(s string) netip.Prefix {
+	p := netip.MustParsePrefix(s)
+	return p
+}
+
+// classify returns the policy table row that applies to addr. Addresses are
+// compared as /128 IPv6 values; IPv4 addresses are mapped to ::ffff:0:0/96
+// first, matching RFC 6724's treatment of v4-mapped addresses. The table's
+// last entry (::/0) matches everything, so the loop always returns before
+// falling off the end.
+func classify(addr netip.Addr) policyEntry {
+	a := to16(addr)
+	for _, e := range defaultPolicyTable {
+		if e.prefix.Contains(a) {
+			return e
+		}
+	}
+	return defaultPolicyTable[len(defaultPolicyTable)-1] // ::/0 catch-all
+}
+
+func to16(addr netip.Addr) netip.Addr {
+	if addr.Is4() {
+		v4 := addr.As4()
+		var v16 [16]byte
+		v16[10] = 0xff
+		v16[11] = 0xff
+		copy(v16[12:], v4[:])
+		return netip.AddrFrom16(v16)
+	}
+	return addr
+}
+
+// classifyScope mirrors net/addrselect.go's classifyScope: loopback and
+// link-local addresses are link-local scope, everything else (including
+// ULA, which RFC 6724 does not special-case) is global scope.
+func classifyScope(addr netip.Addr) scope {
+	if addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return scopeLinkLocal
+	}
+	return scopeGlobal
+}
+
+func commonPrefixLen(a, b netip.Addr) int {
+	a16, b16 := to16(a).As16(), to16(b).As16()
+	n := 0
+	for i := range a16 {
+		x := a16[i] ^ b16[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return n
+}
+
+// Pair is one candidate (source, destination) address for a test endpoint.
+type Pair struct {
+	Source      netip.Addr
+	Destination netip.Addr
+}
+
+// less reports whether p1 is preferred over p2 under RFC 6724 rules 2
+// (matching scope), 5 (matching label), 6 (precedence) and 9 (longest
+// matching prefix), in that order, and names the rule that decided it.
+// Rules 1, 3, 4, 7 and 8 require state (reachability, deprecation, home
+// addresses, tunnel detection) this package doesn't have, so -- like Go's
+// own implementation -- they're treated as always-equal.
+func less(p1, p2 Pair) (preferred bool, decidingRule string) {
+	// Rule 2: Prefer matching scope.
+	scope1 := classifyScope(p1.Destination) == classifyScope(p1.Source)
+	scope2 := classifyScope(p2.Destination) == classifyScope(p2.Source)
+	if scope1 != scope2 {
+		return scope1, "scope"
+	}
+
+	e1, e2 := classify(p1.Destination), classify(p2.Destination)
+
+	// Rule 5: Prefer matching label.
+	srcLabel1 := classify(p1.Source).label
+	srcLabel2 := classify(p2.Source).label
+	label1 := srcLabel1 == e1.label
+	label2 := srcLabel2 == e2.label
+	if label1 != label2 {
+		return label1, "label"
+	}
+
+	// Rule 6: Prefer higher precedence.
+	if e1.precedence != e2.precedence {
+		return e1.precedence > e2.precedence, "precedence"
+	}
+
+	// Rule 9: Use the longest matching prefix (only meaningful within the
+	// same address family; mixed-family ties fall through unchanged).
+	if p1.Destination.BitLen() == p2.Destination.BitLen() {
+		cpl1 := commonPrefixLen(p1.Source, p1.Destination)
+		cpl2 := commonPrefixLen(p2.Source, p2.Destination)
+		if cpl1 != cpl2 {
+			return cpl1 > cpl2, "common_prefix_len"
+		}
+	}
+
+	return false, "tie"
+}
+
+// DestinationChoice is the verdict for one dual-stack test endpoint: which
+// family the client's OS would pick, and why.
+type DestinationChoice struct {
+	PreferredFamily string `json:"preferredFamily"` // "ipv4" or "ipv6"
+	Rule            string `json:"rule"`            // RFC 6724 rule that decided it
+	LabelMismatch   bool   `json:"labelMismatch"`
+}
+
+// ChooseDualStack applies RFC 6724 to a dual-stack endpoint: given the
+// client's own IPv4/IPv6 source addresses and the endpoint's resolved A/AAAA
+// records, it reports which family the OS's destination address selection
+// would prefer. Invalid (zero) destinations mean that family has no record
+// at all (e.g. AAAA-less name); invalid sources mean the client has no
+// address in that family.
+func ChooseDualStack(srcV4, srcV6, dstV4, dstV6 netip.Addr) DestinationChoice {
+	if !dstV6.IsValid() {
+		return DestinationChoice{PreferredFamily: "ipv4", Rule: "no_aaaa"}
+	}
+	if !dstV4.IsValid() {
+		return DestinationChoice{PreferredFamily: "ipv6", Rule: "no_a"}
+	}
+	if !srcV6.IsValid() {
+		return DestinationChoice{PreferredFamily: "ipv4", Rule: "no_ipv6_source"}
+	}
+	if !srcV4.IsValid() {
+		return DestinationChoice{PreferredFamily: "ipv6", Rule: "no_ipv4_source"}
+	}
+
+	p6 := Pair{Source: srcV6, Destination: dstV6}
+	p4 := Pair{Source: srcV4, Destination: dstV4}
+	v6Preferred, rule := less(p6, p4)
+	labelMismatch := rule == "label" && !v6Preferred
+
+	if v6Preferred {
+		return DestinationChoice{PreferredFamily: "ipv6", Rule: rule, LabelMismatch: labelMismatch}
+	}
+	return DestinationChoice{PreferredFamily: "ipv4", Rule: rule, LabelMismatch: labelMismatch}
+}
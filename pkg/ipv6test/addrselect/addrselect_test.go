@@ -0,0 +1,49 @@
+package addrselect
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// TestClassifyOrdering guards against defaultPolicyTable regressing back to
+// an order where a looser prefix (most notably ::/0) shadows a more
+// specific one: classify returns on first match, so the table must stay
+// sorted most-specific-first.
+func TestClassifyOrdering(t *testing.T) {
+	cases := []struct {
+		name       string
+		addr       netip.Addr
+		precedence int
+		label      int
+	}{
+		{"loopback", netip.MustParseAddr("::1"), 50, 0},
+		{"v4_mapped", netip.MustParseAddr("192.0.2.1"), 35, 4},
+		{"6to4", netip.MustParseAddr("2002:c000:0204::1"), 30, 2},
+		{"teredo", netip.MustParseAddr("2001:0:4136:e378::1"), 5, 5},
+		{"ula", netip.MustParseAddr("fc00::1"), 3, 13},
+		{"ordinary_v6", netip.MustParseAddr("2606:4700:4700::1111"), 40, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := classify(c.addr)
+			if got.precedence != c.precedence || got.label != c.label {
+				t.Fatalf("classify(%s) = {precedence:%d label:%d}, want {precedence:%d label:%d}",
+					c.addr, got.precedence, got.label, c.precedence, c.label)
+			}
+		})
+	}
+}
+
+// TestChooseDualStack covers the RFC 6724 outcome the review comment
+// flagged: an ordinary (non-tunnel) IPv6 destination must win over IPv4 on
+// precedence, not fall into a false "::/0 vs ::/0 tie".
+func TestChooseDualStack(t *testing.T) {
+	srcV4 := netip.MustParseAddr("192.0.2.10")
+	srcV6 := netip.MustParseAddr("2001:db8::10")
+
+	got := ChooseDualStack(srcV4, srcV6, netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2606:4700:4700::1111"))
+	want := DestinationChoice{PreferredFamily: "ipv6", Rule: "precedence", LabelMismatch: false}
+	if got != want {
+		t.Fatalf("ChooseDualStack() = %+v, want %+v", got, want)
+	}
+}
@@ -3,7 +3,10 @@ package ipv6test
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
 )
 
 // TokenDetail describes a derived diagnosis token.
@@ -56,8 +59,15 @@ type statusIndex struct {
 	v6mtu Status
 	dsmtu Status
 	v6ns  Status
-	ipv4  *IpObservation
-	ipv6  *IpObservation
+
+	// netcheck4/netcheck6 are the netcheck_v4/netcheck_v6 statuses (see
+	// Runner.runNetcheckProbe); StatusSkipped when no STUN servers were
+	// configured for that family.
+	netcheck4 Status
+	netcheck6 Status
+
+	ipv4 *IpObservation
+	ipv6 *IpObservation
 }
 
 func statusChar(st Status) string {
@@ -125,6 +135,10 @@ func buildStatusIndex(res RunResult) statusIndex {
 			idx.v6mtu = tr.Status
 		case TestDNSV6Resolver:
 			idx.v6ns = tr.Status
+		case TestNetcheckV4:
+			idx.netcheck4 = tr.Status
+		case TestNetcheckV6:
+			idx.netcheck6 = tr.Status
 		}
 	}
 
@@ -158,6 +172,12 @@ func buildStatusIndex(res RunResult) statusIndex {
 	if idx.v6ns == "" {
 		idx.v6ns = StatusSkipped
 	}
+	if idx.netcheck4 == "" {
+		idx.netcheck4 = StatusSkipped
+	}
+	if idx.netcheck6 == "" {
+		idx.netcheck6 = StatusSkipped
+	}
 	return idx
 }
 
@@ -214,6 +234,9 @@ func deriveTokens(res RunResult, st statusIndex, miniPrimary, miniSecondary stri
 	if isGood(st.aaaa) && (isBadish(st.v6mtu) || isBadish(st.dsmtu)) {
 		tokens = append(tokens, "IPv6 MTU")
 	}
+	if isGood(st.aaaa) {
+		tokens = append(tokens, pmtudTokens(res)...)
+	}
 
 	// Need IPv6 encouragement (mirrors JS simplified).
 	if !hasIPv6 || isTunnel(st.ipv6) {
@@ -232,6 +255,14 @@ func deriveTokens(res RunResult, st statusIndex, miniPrimary, miniSecondary stri
 		tokens = append(tokens, "6to4")
 	}
 
+	tokens = append(tokens, dnsProbeTokens(res)...)
+	tokens = append(tokens, addrSelectionTokens(res)...)
+	tokens = append(tokens, rdnsTokens(res)...)
+	tokens = append(tokens, ipmetaTokens(res)...)
+	tokens = append(tokens, dialPolicyTokens(res)...)
+	tokens = append(tokens, resolverProbeTokens(res)...)
+	tokens = append(tokens, netcheckTokens(res)...)
+
 	// Preserve mini_primary/minor confusion token if nothing added.
 	if len(tokens) == 0 {
 		tokens = append(tokens, miniPrimary)
@@ -239,6 +270,214 @@ func deriveTokens(res RunResult, st statusIndex, miniPrimary, miniSecondary stri
 	return tokens
 }
 
+// dnsProbeTokens inspects the dnsprobe-backed test results for the
+// findings runDNSProbe records in their Notes/Status: resolver-path fragmentation,
+// DNSSEC stripping, and AAAA-only/glue-less delegations.
+func dnsProbeTokens(res RunResult) []string {
+	var tokens []string
+	for _, tr := range res.Results {
+		if !IsDNSTest(tr.Name) {
+			continue
+		}
+		switch tr.Name {
+		case TestDNSEDNS0:
+			if strings.Contains(tr.Notes, "edns0_pmtu_fragmentation") {
+				tokens = append(tokens, "dns:edns0_pmtu_fragmentation")
+			}
+		case TestDNSDNSSECOk:
+			if strings.Contains(tr.Notes, "no RRSIG") {
+				tokens = append(tokens, "dns:dnssec_unsupported")
+			}
+		case TestDNSAAAA, TestDNSGlueV6:
+			if tr.Status == StatusBad || tr.Status == StatusTimeout {
+				tokens = append(tokens, "dns:aaaa_missing")
+			}
+		}
+	}
+	return tokens
+}
+
+// pmtudTokens reports the pkg/ipv6test/pmtud outcome for ipv6_mtu/
+// dual_stack_mtu: a confirmed PMTU black-hole (large payloads silently
+// drop while the RFC 8200 minimum still works), ICMPv6 itself looking
+// filtered on this path, and the concrete effective MTU discovered
+// (e.g. "mtu:1492") so an operator gets a number instead of just a
+// pass/fail.
+func pmtudTokens(res RunResult) []string {
+	var tokens []string
+	for _, tr := range res.Results {
+		if tr.Name != TestIPv6MTU && tr.Name != TestDualStackMTU {
+			continue
+		}
+		switch {
+		case strings.Contains(tr.Notes, "pmtud_blackhole"):
+			tokens = append(tokens, "mtu:blackhole")
+		case strings.Contains(tr.Notes, "pmtud_icmp_filtered"):
+			tokens = append(tokens, "mtu:icmp_filtered")
+		case tr.DiscoveredMTU > 0:
+			tokens = append(tokens, fmt.Sprintf("mtu:%d", tr.DiscoveredMTU))
+		}
+	}
+	return tokens
+}
+
+// addrSelectionTokens turns the RFC 6724 prediction (res.AddrSelection)
+// into findings: a surprising label-mismatch preference, or a mismatch
+// between the prediction and what the dual_stack probe actually used.
+func addrSelectionTokens(res RunResult) []string {
+	sel := res.AddrSelection
+	if sel == nil {
+		return nil
+	}
+	var tokens []string
+	if sel.LabelMismatch && sel.PredictedFamily == "ipv4" {
+		tokens = append(tokens, "addrselect:prefers_ipv4_label_mismatch")
+	}
+	if sel.ActualFamily != "" && !sel.Matches {
+		tokens = append(tokens, "addrselect:prediction_mismatch")
+	}
+	return tokens
+}
+
+// rdnsTokens inspects the rdns_v4/rdns_v6 results for missing PTRs,
+// mismatched forward-confirmation, and generic-looking rDNS names.
+func rdnsTokens(res RunResult) []string {
+	var tokens []string
+	for _, tr := range res.Results {
+		switch tr.Name {
+		case TestRDNSv4, TestRDNSv6:
+		default:
+			continue
+		}
+		switch {
+		case tr.Status == StatusBad && tr.PTRName == "":
+			if tr.Name == TestRDNSv6 {
+				tokens = append(tokens, "rdns:v6_missing")
+			} else {
+				tokens = append(tokens, "rdns:v4_missing")
+			}
+		case tr.PTRName != "" && !tr.ForwardConfirmed:
+			tokens = append(tokens, "rdns:forward_mismatch")
+		}
+		if strings.Contains(tr.Notes, "generic rDNS name") {
+			tokens = append(tokens, "rdns:generic")
+		}
+	}
+	return tokens
+}
+
+// tunnelBrokerASNs is a small curated set of ASNs known to operate public
+// IPv6 tunnel brokers, so traffic routed through one can be flagged even
+// when it doesn't use a recognizable 6to4/Teredo prefix (a 6in4 tunnel
+// over HE.net, for instance, hands out regular-looking native IPv6
+// addresses out of the broker's own space). Not exhaustive.
+var tunnelBrokerASNs = map[int]string{
+	6939: "Hurricane Electric (tunnelbroker.net)",
+}
+
+// ipmetaTokens flags findings from the ipmeta-enriched IPv4/IPv6
+// observations (see Runner.enrichIPMeta): a tunnel broker behind the
+// IPv6 address, both by the legacy teredo/6to4 prefix detection and by
+// ASN (tunnelBrokerASNs), a unique-local address leaking onto the public
+// Internet, and the two families resolving to different origin ASNs or
+// GeoIP countries.
+func ipmetaTokens(res RunResult) []string {
+	var tokens []string
+	if res.IPv6 != nil && res.IPv6.Subtype != "" {
+		tokens = append(tokens, "tunnel_broker_detected")
+	}
+	if res.IPv6 != nil && tunnelBrokerASNs[res.IPv6.ASN] != "" {
+		tokens = append(tokens, "asn:tunnel_broker")
+	}
+	if (res.IPv4 != nil && res.IPv4.IsULA) || (res.IPv6 != nil && res.IPv6.IsULA) {
+		tokens = append(tokens, "ula_leaking")
+	}
+	if res.IPv4 != nil && res.IPv6 != nil && res.IPv4.ASN != 0 && res.IPv6.ASN != 0 && res.IPv4.ASN != res.IPv6.ASN {
+		tokens = append(tokens, "asn_mismatch_v4_v6")
+	}
+	if res.IPv4 != nil && res.IPv6 != nil && res.IPv4.Country != "" && res.IPv6.Country != "" && res.IPv4.Country != res.IPv6.Country {
+		tokens = append(tokens, "country:mismatch")
+	}
+	return tokens
+}
+
+// dialPolicyTokens inspects the dialpolicy outcome recorded on each
+// HTTP-backed TestResult (see Runner.runSingle): a Happy Eyeballs race
+// that had to fall back to IPv4, and a policy-driven exclusion of
+// candidate IPv6 addresses that a dual-stack host actually had.
+func dialPolicyTokens(res RunResult) []string {
+	var tokens []string
+	happyEyeballs := res.AddressPolicy == string(dialpolicy.HappyEyeballs)
+	for _, tr := range res.Results {
+		if tr.FilteredFamily == "ipv6" {
+			tokens = append(tokens, "ipv6:filtered_out")
+		}
+		if happyEyeballs && tr.Fallback && tr.FamilyUsed == "ipv4" {
+			tokens = append(tokens, "happy_eyeballs:fallback_v4")
+		}
+	}
+	return tokens
+}
+
+// resolverProbeTokens inspects resolver_aaaa/resolver_doh6 (see
+// Runner.runResolverProbe): a configured resolver missing AAAA entirely, a
+// truncated UDP answer with no working TCP fallback, and a DoH resolver
+// confirmed reachable over IPv6.
+func resolverProbeTokens(res RunResult) []string {
+	var tokens []string
+	for _, tr := range res.Results {
+		switch tr.Name {
+		case TestResolverAAAA:
+			if tr.Status == StatusBad && strings.Contains(tr.Notes, "no_aaaa") {
+				tokens = append(tokens, "resolver:no_aaaa")
+			}
+			if strings.Contains(tr.Notes, "no_tcp_fallback") {
+				tokens = append(tokens, "resolver:truncated_no_tcp_fallback")
+			}
+		case TestResolverDoH6:
+			if tr.Status == StatusOK {
+				tokens = append(tokens, "resolver:doh_v6_ok")
+			}
+		}
+	}
+	return tokens
+}
+
+// netcheckTokens inspects netcheck_v4/netcheck_v6 (see
+// Runner.runNetcheckProbe): address-dependent STUN mapping (our proxy for
+// symmetric NAT), a NAT that doesn't hairpin traffic back to the sender,
+// an IPv4 address in the RFC 6598 CGNAT range, and an IPv6 configuration
+// that's link-local- or ULA-only despite having an interface address at
+// all.
+func netcheckTokens(res RunResult) []string {
+	var tokens []string
+	for _, tr := range res.Results {
+		switch tr.Name {
+		case TestNetcheckV4:
+			if tr.NATType == "address_dependent" {
+				tokens = append(tokens, "nat:symmetric")
+			}
+			if tr.CGNATDetected {
+				tokens = append(tokens, "cgnat:detected")
+			}
+			if tr.HairpinTested && !tr.HairpinWorks {
+				tokens = append(tokens, "nat:hairpin_broken")
+			}
+		case TestNetcheckV6:
+			if tr.IPv6LinkLocalOnly {
+				tokens = append(tokens, "ipv6:link_local_only")
+			}
+			if tr.IPv6ULAOnly {
+				tokens = append(tokens, "ipv6:ula_only")
+			}
+			if tr.HairpinTested && !tr.HairpinWorks {
+				tokens = append(tokens, "nat:hairpin_broken")
+			}
+		}
+	}
+	return tokens
+}
+
 func isTunnel(ip *IpObservation) bool {
 	return isTunnelType(ip, "Teredo") || isTunnelType(ip, "6to4")
 }
@@ -258,6 +497,20 @@ func isBadish(st Status) bool {
 	return st == StatusBad || st == StatusTimeout || st == StatusError
 }
 
+// parseMTUToken reports whether t is a dynamic "mtu:<size>" token (see
+// pmtudTokens) and, if so, the size it carries.
+func parseMTUToken(t string) (int, bool) {
+	rest, ok := strings.CutPrefix(t, "mtu:")
+	if !ok || rest == "blackhole" || rest == "icmp_filtered" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
 func dedupe(in []string) []string {
 	seen := make(map[string]struct{}, len(in))
 	var out []string
@@ -294,6 +547,16 @@ func computeScores(tokens []TokenDetail) (int, int) {
 func expandTokens(tokens []string) []TokenDetail {
 	var details []TokenDetail
 	for _, t := range tokens {
+		if mtu, ok := parseMTUToken(t); ok {
+			details = append(details, TokenDetail{
+				Token:     t,
+				ScoreIPv4: 10,
+				ScoreIPv6: 10,
+				Color:     colorName(intBlue),
+				Message:   fmt.Sprintf("Effective path MTU discovered between you and this host: %d bytes.", mtu),
+			})
+			continue
+		}
 		entry, ok := scoreTable[t]
 		if !ok {
 			details = append(details, TokenDetail{
@@ -325,42 +588,68 @@ func expandTokens(tokens []string) []TokenDetail {
 
 // scoreTable and messageTable are trimmed ports of templates/js/inc/scores.js and messages.js.
 var scoreTable = map[string][3]int{
-	"6to4":                     {7, 7, intBlue},
-	"teredo":                   {7, 7, intBlue},
-	"teredo-v4pref":            {10, 7, intBlue},
-	"teredo-minimum":           {10, 0, intBlue},
-	"IPv6 MTU":                 {1, 1, intRed},
-	"dualstack:ipv4_preferred": {10, 10, intGreen},
-	"dualstack:ipv6_preferred": {10, 10, intGreen},
-	"dualstack:slow":           {7, 7, intBlue},
-	"ipv4_only":                {10, 0, intBlue},
-	"ipv4_only:ds_good":        {10, 0, intBlue},
-	"ipv4_only:ds_slow":        {5, 0, intRed},
-	"ipv4_only:ds_timeout":     {5, 0, intRed},
-	"ipv4_slow":                {5, 10, intRed},
-	"ipv6_only":                {0, 10, intBlue},
-	"ipv6_slow":                {10, 5, intRed},
-	"ipv6_timeout":             {10, 0, intRed},
-	"ipv6:nodns":               {10, 0, intRed},
-	"broken_ipv6":              {0, 0, intRed},
-	"webfilter:blocked":        {-1, -1, intOrange},
-	"webfilter:dsboth":         {10, 10, intOrange},
-	"webfilter:addons":         {10, 10, intOrange},
-	"webfilter:firefox":        {10, 10, intOrange},
-	"v6ns:ok":                  {10, 10, intGreen},
-	"v6ns:bad":                 {10, 9, intBlue},
-	"ip_timeout:firefox":       {10, 10, intRed},
-	"ipv4:no_address":          {10, 10, intBlue},
-	"ipv6:no_address":          {10, 10, intRed},
-	"no_address":               {10, 10, intRed},
-	"dualstack:safe":           {10, 10, intGreen},
-	"needs_ipv6":               {10, 10, intBlue},
-	"dualstack:unsafe":         {10, 10, intRed},
-	"dualstack:mtu":            {10, 10, intRed},
-	"proxy_via":                {10, 10, intOrange},
-	"proxy_via_dumb":           {10, 10, intOrange},
-	"broken":                   {0, 0, intBlue},
-	"avoids_ipv6":              {10, 10, intOrange},
+	"6to4":                                   {7, 7, intBlue},
+	"teredo":                                 {7, 7, intBlue},
+	"teredo-v4pref":                          {10, 7, intBlue},
+	"teredo-minimum":                         {10, 0, intBlue},
+	"IPv6 MTU":                               {1, 1, intRed},
+	"dualstack:ipv4_preferred":               {10, 10, intGreen},
+	"dualstack:ipv6_preferred":               {10, 10, intGreen},
+	"dualstack:slow":                         {7, 7, intBlue},
+	"ipv4_only":                              {10, 0, intBlue},
+	"ipv4_only:ds_good":                      {10, 0, intBlue},
+	"ipv4_only:ds_slow":                      {5, 0, intRed},
+	"ipv4_only:ds_timeout":                   {5, 0, intRed},
+	"ipv4_slow":                              {5, 10, intRed},
+	"ipv6_only":                              {0, 10, intBlue},
+	"ipv6_slow":                              {10, 5, intRed},
+	"ipv6_timeout":                           {10, 0, intRed},
+	"ipv6:nodns":                             {10, 0, intRed},
+	"broken_ipv6":                            {0, 0, intRed},
+	"webfilter:blocked":                      {-1, -1, intOrange},
+	"webfilter:dsboth":                       {10, 10, intOrange},
+	"webfilter:addons":                       {10, 10, intOrange},
+	"webfilter:firefox":                      {10, 10, intOrange},
+	"v6ns:ok":                                {10, 10, intGreen},
+	"v6ns:bad":                               {10, 9, intBlue},
+	"ip_timeout:firefox":                     {10, 10, intRed},
+	"ipv4:no_address":                        {10, 10, intBlue},
+	"ipv6:no_address":                        {10, 10, intRed},
+	"no_address":                             {10, 10, intRed},
+	"dualstack:safe":                         {10, 10, intGreen},
+	"needs_ipv6":                             {10, 10, intBlue},
+	"dualstack:unsafe":                       {10, 10, intRed},
+	"dualstack:mtu":                          {10, 10, intRed},
+	"proxy_via":                              {10, 10, intOrange},
+	"proxy_via_dumb":                         {10, 10, intOrange},
+	"broken":                                 {0, 0, intBlue},
+	"avoids_ipv6":                            {10, 10, intOrange},
+	"tunnel_broker_detected":                 {10, 7, intBlue},
+	"ula_leaking":                            {5, 5, intRed},
+	"asn_mismatch_v4_v6":                     {10, 10, intBlue},
+	"asn:tunnel_broker":                      {10, 6, intBlue},
+	"country:mismatch":                       {10, 10, intBlue},
+	"mtu:blackhole":                          {10, 2, intRed},
+	"mtu:icmp_filtered":                      {10, 8, intOrange},
+	"dns:edns0_pmtu_fragmentation":           {10, 3, intRed},
+	"dns:dnssec_unsupported":                 {10, 9, intOrange},
+	"dns:aaaa_missing":                       {10, 2, intRed},
+	"addrselect:prefers_ipv4_label_mismatch": {10, 7, intOrange},
+	"addrselect:prediction_mismatch":         {10, 10, intBlue},
+	"rdns:v4_missing":                        {7, 10, intOrange},
+	"rdns:v6_missing":                        {10, 7, intOrange},
+	"rdns:forward_mismatch":                  {5, 5, intRed},
+	"rdns:generic":                           {10, 10, intBlue},
+	"happy_eyeballs:fallback_v4":             {10, 7, intBlue},
+	"ipv6:filtered_out":                      {10, 10, intBlue},
+	"resolver:no_aaaa":                       {10, 5, intOrange},
+	"resolver:doh_v6_ok":                     {10, 10, intGreen},
+	"resolver:truncated_no_tcp_fallback":     {10, 3, intRed},
+	"nat:symmetric":                          {5, 10, intOrange},
+	"nat:hairpin_broken":                     {7, 7, intOrange},
+	"cgnat:detected":                         {5, 10, intRed},
+	"ipv6:link_local_only":                   {10, 2, intRed},
+	"ipv6:ula_only":                          {10, 2, intRed},
 }
 
 var intGreen = 1
@@ -384,42 +673,68 @@ func colorName(code int) string {
 }
 
 var messageTable = map[string]string{
-	"6to4":                     "You appear to be using a public 6to4 gateway; performance may suffer. Native IPv6 is preferred.",
-	"teredo":                   "Your IPv6 connection appears to be using Teredo, a public IPv4/IPv6 gateway; quality may suffer.",
-	"teredo-v4pref":            "Your IPv6 connection uses Teredo as a last resort; IPv4 will be preferred on dual-stack sites.",
-	"teredo-minimum":           "Your IPv6 connection uses Teredo and only works to literal IPs; not useful for browsing IPv6 sites.",
-	"IPv6 MTU":                 "IPv6 works but large packets fail; check MTU and allow ICMPv6 Packet Too Big.",
-	"dualstack:ipv4_preferred": "Dual-stack reachable; browser prefers IPv4.",
-	"dualstack:ipv6_preferred": "Dual-stack reachable; browser prefers IPv6.",
-	"dualstack:slow":           "Dual-stack reachable but browser slows down when both families are offered.",
-	"ipv4_only":                "You appear to be able to browse the IPv4 Internet only. You will not be able to reach IPv6-only sites.",
-	"ipv4_only:ds_good":        "When a publisher offers both IPv4 and IPv6, your browser takes IPv4 without delay.",
-	"ipv4_only:ds_slow":        "When a publisher offers both IPv4 and IPv6, your browser is slower than IPv4-only sites.",
-	"ipv4_only:ds_timeout":     "When a publisher offers both IPv4 and IPv6, your browser times out trying to connect.",
-	"ipv4_slow":                "Connections to IPv4 are slow, but functional.",
-	"ipv6_only":                "You appear to be able to browse the IPv6 Internet only. You have no access to IPv4.",
-	"ipv6_slow":                "Connections to IPv6 are slow, but functional.",
-	"ipv6_timeout":             "Connections to IPv6-only sites are timing out.",
-	"ipv6:nodns":               "IPv6 connections work, but DNS lookups do not use IPv6 (no AAAA).",
-	"broken_ipv6":              "You appear to have IPv6 configured, but it completely fails for IPv6 sites.",
-	"webfilter:blocked":        "Tests appear blocked by a firewall or browser filter; critical tests failed.",
-	"webfilter:dsboth":         "Dual-stack tests appear blocked by a browser or network filter.",
-	"webfilter:addons":         "Browser blocked test URLs; alternate methods may be incomplete.",
-	"webfilter:firefox":        "Likely a Firefox add-on (e.g., NoScript/AdBlock) blocked tests.",
-	"v6ns:ok":                  "Your DNS server appears to have IPv6 Internet access.",
-	"v6ns:bad":                 "Your DNS server appears to have no IPv6 Internet access or is not configured to use it.",
-	"ip_timeout:firefox":       "Firefox add-on likely caused IP-based tests to fail.",
-	"ipv4:no_address":          "No IPv4 address detected.",
-	"ipv6:no_address":          "No IPv6 address detected.",
-	"no_address":               "IP addresses could not be detected due to interference from browser add-ons.",
-	"dualstack:safe":           "Good news! Your current configuration will continue to work as sites enable IPv6.",
-	"needs_ipv6":               "To ensure the best Internet performance and connectivity, ask your ISP about native IPv6.",
-	"dualstack:unsafe":         "Our tests show dual-stack readiness is unsafe; IPv6 may cause problems.",
-	"dualstack:mtu":            "MTU issues detected; IPv6-only sites may fail or load slowly.",
-	"proxy_via":                "A proxy was detected; tests reflect the proxy, not the local host.",
-	"proxy_via_dumb":           "A proxy was detected; tests reflect the proxy, not the local host.",
-	"broken":                   "We have suggestions to help you fix your system.",
-	"avoids_ipv6":              "Browser has working IPv6 but is avoiding using it; this is concerning.",
+	"6to4":                                   "You appear to be using a public 6to4 gateway; performance may suffer. Native IPv6 is preferred.",
+	"teredo":                                 "Your IPv6 connection appears to be using Teredo, a public IPv4/IPv6 gateway; quality may suffer.",
+	"teredo-v4pref":                          "Your IPv6 connection uses Teredo as a last resort; IPv4 will be preferred on dual-stack sites.",
+	"teredo-minimum":                         "Your IPv6 connection uses Teredo and only works to literal IPs; not useful for browsing IPv6 sites.",
+	"IPv6 MTU":                               "IPv6 works but large packets fail; check MTU and allow ICMPv6 Packet Too Big.",
+	"dualstack:ipv4_preferred":               "Dual-stack reachable; browser prefers IPv4.",
+	"dualstack:ipv6_preferred":               "Dual-stack reachable; browser prefers IPv6.",
+	"dualstack:slow":                         "Dual-stack reachable but browser slows down when both families are offered.",
+	"ipv4_only":                              "You appear to be able to browse the IPv4 Internet only. You will not be able to reach IPv6-only sites.",
+	"ipv4_only:ds_good":                      "When a publisher offers both IPv4 and IPv6, your browser takes IPv4 without delay.",
+	"ipv4_only:ds_slow":                      "When a publisher offers both IPv4 and IPv6, your browser is slower than IPv4-only sites.",
+	"ipv4_only:ds_timeout":                   "When a publisher offers both IPv4 and IPv6, your browser times out trying to connect.",
+	"ipv4_slow":                              "Connections to IPv4 are slow, but functional.",
+	"ipv6_only":                              "You appear to be able to browse the IPv6 Internet only. You have no access to IPv4.",
+	"ipv6_slow":                              "Connections to IPv6 are slow, but functional.",
+	"ipv6_timeout":                           "Connections to IPv6-only sites are timing out.",
+	"ipv6:nodns":                             "IPv6 connections work, but DNS lookups do not use IPv6 (no AAAA).",
+	"broken_ipv6":                            "You appear to have IPv6 configured, but it completely fails for IPv6 sites.",
+	"webfilter:blocked":                      "Tests appear blocked by a firewall or browser filter; critical tests failed.",
+	"webfilter:dsboth":                       "Dual-stack tests appear blocked by a browser or network filter.",
+	"webfilter:addons":                       "Browser blocked test URLs; alternate methods may be incomplete.",
+	"webfilter:firefox":                      "Likely a Firefox add-on (e.g., NoScript/AdBlock) blocked tests.",
+	"v6ns:ok":                                "Your DNS server appears to have IPv6 Internet access.",
+	"v6ns:bad":                               "Your DNS server appears to have no IPv6 Internet access or is not configured to use it.",
+	"ip_timeout:firefox":                     "Firefox add-on likely caused IP-based tests to fail.",
+	"ipv4:no_address":                        "No IPv4 address detected.",
+	"ipv6:no_address":                        "No IPv6 address detected.",
+	"no_address":                             "IP addresses could not be detected due to interference from browser add-ons.",
+	"dualstack:safe":                         "Good news! Your current configuration will continue to work as sites enable IPv6.",
+	"needs_ipv6":                             "To ensure the best Internet performance and connectivity, ask your ISP about native IPv6.",
+	"dualstack:unsafe":                       "Our tests show dual-stack readiness is unsafe; IPv6 may cause problems.",
+	"dualstack:mtu":                          "MTU issues detected; IPv6-only sites may fail or load slowly.",
+	"proxy_via":                              "A proxy was detected; tests reflect the proxy, not the local host.",
+	"proxy_via_dumb":                         "A proxy was detected; tests reflect the proxy, not the local host.",
+	"broken":                                 "We have suggestions to help you fix your system.",
+	"avoids_ipv6":                            "Browser has working IPv6 but is avoiding using it; this is concerning.",
+	"tunnel_broker_detected":                 "Your IPv6 address routes through a tunnel broker (6to4/Teredo/6rd) rather than native IPv6.",
+	"ula_leaking":                            "A unique-local address (fc00::/7) was observed as your public-facing address; this usually indicates a misconfigured NAT64/NAT66 or a internal address leaking to the Internet.",
+	"asn_mismatch_v4_v6":                     "Your IPv4 and IPv6 addresses belong to different networks (ASNs); this is common with tunnel brokers and some dual-stack ISPs, but worth knowing if you're debugging routing.",
+	"asn:tunnel_broker":                      "Your IPv6 address's ASN belongs to a known public tunnel broker rather than your access ISP.",
+	"country:mismatch":                       "GeoIP places your IPv4 and IPv6 addresses in different countries; this is common with tunnel brokers and some CDNs/VPNs, but worth knowing if you're debugging geolocation.",
+	"mtu:blackhole":                          "Large IPv6 packets silently disappear while the RFC 8200 minimum (1280 bytes) gets through; something on the path is dropping ICMPv6 Packet Too Big messages.",
+	"mtu:icmp_filtered":                      "ICMPv6 itself appears to be filtered on this path (no Packet Too Big, no echo replies), even though HTTP at the same sizes works; PMTUD can silently fail here.",
+	"dns:edns0_pmtu_fragmentation":           "A large EDNS0 DNS response truncates but a smaller one succeeds; your resolver path likely has a PMTU or fragmentation problem.",
+	"dns:dnssec_unsupported":                 "DNSSEC-OK queries are not returning RRSIG/DNSKEY records; DNSSEC validation may not work through this path.",
+	"dns:aaaa_missing":                       "AAAA lookups for this name are failing or missing glue; IPv6-only resolution is not working.",
+	"addrselect:prefers_ipv4_label_mismatch": "Your OS will prefer IPv4 on dual-stack sites despite AAAA existing, due to an RFC 6724 address-label mismatch.",
+	"addrselect:prediction_mismatch":         "RFC 6724 destination address selection predicted a different family than the dual-stack probe actually used; your OS/resolver may diverge from the standard policy table.",
+	"rdns:v4_missing":                        "No reverse DNS (PTR) record for your IPv4 address.",
+	"rdns:v6_missing":                        "No reverse DNS (PTR) record for your IPv6 address.",
+	"rdns:forward_mismatch":                  "Your reverse DNS (PTR) name does not resolve back to your address; rDNS hygiene is broken.",
+	"rdns:generic":                           "Your reverse DNS name appears to be an ISP-assigned generic/dynamic name.",
+	"happy_eyeballs:fallback_v4":             "A Happy Eyeballs race between IPv4 and IPv6 fell back to IPv4; the IPv6 path was slower or failed outright.",
+	"ipv6:filtered_out":                      "This host has IPv6 addresses, but the configured address-selection policy excluded them from this test.",
+	"resolver:no_aaaa":                       "At least one configured resolver did not return an AAAA record for a known dual-stack name.",
+	"resolver:doh_v6_ok":                     "A configured DNS-over-HTTPS resolver is itself reachable over IPv6.",
+	"resolver:truncated_no_tcp_fallback":     "A resolver's UDP answer was truncated and the TCP retry also failed; EDNS0/TCP fallback is broken on this path.",
+	"nat:symmetric":                          "STUN observed a different mapped address/port from different servers; your NAT likely uses address/port-dependent (symmetric) mapping, which breaks many peer-to-peer protocols.",
+	"nat:hairpin_broken":                     "Traffic sent to your own external (STUN-mapped) address did not loop back; your router does not support NAT hairpinning.",
+	"cgnat:detected":                         "Your IPv4 address is in the RFC 6598 Carrier-Grade NAT range (100.64.0.0/10); you are sharing a public IPv4 address with other subscribers.",
+	"ipv6:link_local_only":                   "Your only IPv6 address is link-local (fe80::/10); you have no usable global IPv6 connectivity.",
+	"ipv6:ula_only":                          "Your only IPv6 address is a unique-local address (fc00::/7); you have no usable global IPv6 connectivity.",
 }
 
 var moreInfoTable = map[string]string{
@@ -0,0 +1,292 @@
+// Package dialpolicy implements the address-family selection an
+// http.Transport applies when dialing a dual-stack host: plain
+// IPv4-only/IPv6-only filtering, a family preference with fallback, or a
+// Happy Eyeballs (RFC 8305) race between the two families. It exists so
+// Runner can faithfully reproduce "the browser prefers IPv4 on
+// dual-stack" scenarios instead of only inferring them after the fact
+// from which family a plain net/http dial happened to pick.
+package dialpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Policy selects how DialContextFunc orders and filters the candidate
+// addresses a hostname resolves to.
+type Policy string
+
+const (
+	SystemDefault Policy = "system_default" // dial candidates in the resolver's own order
+	IPv4Only      Policy = "ipv4_only"
+	IPv6Only      Policy = "ipv6_only"
+	IPv4Prefer    Policy = "ipv4_prefer" // try IPv4 candidates first, fall back to IPv6
+	IPv6Prefer    Policy = "ipv6_prefer" // try IPv6 candidates first, fall back to IPv4
+	HappyEyeballs Policy = "happy_eyeballs"
+)
+
+// DefaultHeadStart is how long HappyEyeballs waits for the preferred
+// family to connect before racing the other family alongside it.
+const DefaultHeadStart = 250 * time.Millisecond
+
+// Outcome records what actually happened during one dial, for the caller
+// to read back after the request completes (see NewContext).
+type Outcome struct {
+	FamilyUsed      string // "ipv4" or "ipv6"
+	CandidatesTried int
+	Fallback        bool   // the preferred/primary family failed and the other was used instead
+	FilteredFamily  string // a family that had candidate addresses but was excluded by policy
+}
+
+type outcomeKey struct{}
+
+// NewContext returns a child of ctx carrying a fresh Outcome that the
+// dial function returned by DialContextFunc will populate, plus a
+// pointer the caller reads back once the request using ctx completes.
+func NewContext(ctx context.Context) (context.Context, *Outcome) {
+	o := &Outcome{}
+	return context.WithValue(ctx, outcomeKey{}, o), o
+}
+
+// FromContext returns the Outcome stashed by NewContext, or nil if ctx
+// doesn't carry one.
+func FromContext(ctx context.Context) *Outcome {
+	o, _ := ctx.Value(outcomeKey{}).(*Outcome)
+	return o
+}
+
+// DialContextFunc returns a dial function suitable for
+// http.Transport.DialContext that resolves the target host, applies
+// policy, and records its Outcome (via FromContext) on ctx. preferred is
+// "ipv4" or "ipv6" and only matters for HappyEyeballs (default "ipv6",
+// matching RFC 8305's recommendation); headStart <= 0 uses
+// DefaultHeadStart.
+func DialContextFunc(policy Policy, preferred string, headStart time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	if preferred == "" {
+		preferred = "ipv6"
+	}
+	if headStart <= 0 {
+		headStart = DefaultHeadStart
+	}
+	dialer := &net.Dialer{}
+	resolver := net.DefaultResolver
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			// Already a literal address: nothing to select between.
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		ips, err := resolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		v4, v6 := splitByFamily(ips)
+		outcome := FromContext(ctx)
+
+		if policy == IPv4Only && len(v6) > 0 {
+			recordFiltered(outcome, "ipv6")
+		}
+		if policy == IPv6Only && len(v4) > 0 {
+			recordFiltered(outcome, "ipv4")
+		}
+
+		if policy == HappyEyeballs {
+			primary, secondary, primaryFamily, secondaryFamily := v6, v4, "ipv6", "ipv4"
+			if preferred == "ipv4" {
+				primary, secondary, primaryFamily, secondaryFamily = v4, v6, "ipv4", "ipv6"
+			}
+			return dialHappyEyeballs(ctx, dialer, network, port, primary, secondary, primaryFamily, secondaryFamily, headStart, outcome)
+		}
+		return dialSequential(ctx, dialer, network, port, orderCandidates(policy, ips), outcome)
+	}
+}
+
+type taggedIP struct {
+	ip     net.IP
+	family string
+}
+
+func splitByFamily(ips []net.IP) (v4, v6 []net.IP) {
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	return v4, v6
+}
+
+func tag(ips []net.IP, family string) []taggedIP {
+	out := make([]taggedIP, 0, len(ips))
+	for _, ip := range ips {
+		out = append(out, taggedIP{ip: ip, family: family})
+	}
+	return out
+}
+
+// orderCandidates applies policy's filtering/ordering to a host's
+// resolved addresses. SystemDefault preserves the resolver's own order
+// (tagging each IP's family individually) rather than imposing one.
+func orderCandidates(policy Policy, ips []net.IP) []taggedIP {
+	v4, v6 := splitByFamily(ips)
+	switch policy {
+	case IPv4Only:
+		return tag(v4, "ipv4")
+	case IPv6Only:
+		return tag(v6, "ipv6")
+	case IPv4Prefer:
+		return append(tag(v4, "ipv4"), tag(v6, "ipv6")...)
+	case IPv6Prefer:
+		return append(tag(v6, "ipv6"), tag(v4, "ipv4")...)
+	default:
+		out := make([]taggedIP, 0, len(ips))
+		for _, ip := range ips {
+			family := "ipv4"
+			if ip.To4() == nil {
+				family = "ipv6"
+			}
+			out = append(out, taggedIP{ip: ip, family: family})
+		}
+		return out
+	}
+}
+
+// dialSequential tries each candidate in order until one connects.
+func dialSequential(ctx context.Context, dialer *net.Dialer, network, port string, candidates []taggedIP, outcome *Outcome) (net.Conn, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("dialpolicy: no candidate addresses for policy")
+	}
+	firstFamily := candidates[0].family
+	var lastErr error
+	for i, c := range candidates {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(c.ip.String(), port))
+		if err == nil {
+			recordOutcome(outcome, c.family, i+1, c.family != firstFamily)
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// dialFirst tries each IP of a single family in order, returning the
+// first that connects.
+func dialFirst(ctx context.Context, dialer *net.Dialer, network string, ips []net.IP, port string) (net.Conn, error) {
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("dialpolicy: no addresses")
+	}
+	return nil, lastErr
+}
+
+// raced is one candidate family's outcome from the goroutines
+// dialHappyEyeballs races against each other.
+type raced struct {
+	conn   net.Conn
+	err    error
+	family string
+}
+
+// dialHappyEyeballs implements RFC 8305: dial the primary family
+// immediately, and after headStart (or immediately, if the primary
+// family has no candidates at all) start racing the secondary family
+// alongside it. The first successful connection wins; outcome.Fallback
+// is set when that connection used the secondary family.
+func dialHappyEyeballs(ctx context.Context, dialer *net.Dialer, network, port string, primaryIPs, secondaryIPs []net.IP, primaryFamily, secondaryFamily string, headStart time.Duration, outcome *Outcome) (net.Conn, error) {
+	if len(primaryIPs) == 0 {
+		return dialSequential(ctx, dialer, network, port, tag(secondaryIPs, secondaryFamily), outcome)
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan raced, 2)
+	pending := 1
+	started := 1
+
+	go func() {
+		conn, err := dialFirst(raceCtx, dialer, network, primaryIPs, port)
+		results <- raced{conn, err, primaryFamily}
+	}()
+
+	if len(secondaryIPs) > 0 {
+		timer := time.NewTimer(headStart)
+		defer timer.Stop()
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				recordOutcome(outcome, res.family, started, false)
+				return res.conn, nil
+			}
+			// Primary failed outright before the head start elapsed;
+			// race the secondary immediately.
+		case <-timer.C:
+		}
+		started = 2
+		pending++
+		go func() {
+			conn, err := dialFirst(raceCtx, dialer, network, secondaryIPs, port)
+			results <- raced{conn, err, secondaryFamily}
+		}()
+	}
+
+	var lastErr error
+	for pending > 0 {
+		res := <-results
+		pending--
+		if res.err == nil {
+			recordOutcome(outcome, res.family, started, res.family != primaryFamily)
+			if pending > 0 {
+				go drainRaced(results, pending)
+			}
+			return res.conn, nil
+		}
+		lastErr = res.err
+	}
+	return nil, lastErr
+}
+
+// drainRaced waits out the dials still in flight after dialHappyEyeballs has
+// already returned a winner, closing any connection that shows up late
+// instead of leaking it: cancelling raceCtx only stops a dial that hasn't
+// connected yet, so a loser that was already through TCP/TLS handshaking
+// when the winner arrived still hands back an open net.Conn on results.
+func drainRaced(results <-chan raced, pending int) {
+	for i := 0; i < pending; i++ {
+		if res := <-results; res.conn != nil {
+			res.conn.Close()
+		}
+	}
+}
+
+func recordOutcome(o *Outcome, family string, tried int, fallback bool) {
+	if o == nil {
+		return
+	}
+	o.FamilyUsed = family
+	o.CandidatesTried = tried
+	o.Fallback = fallback
+}
+
+func recordFiltered(o *Outcome, family string) {
+	if o == nil {
+		return
+	}
+	o.FilteredFamily = family
+}
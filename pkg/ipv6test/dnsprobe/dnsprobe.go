@@ -0,0 +1,537 @@
+// Package dnsprobe issues direct DNS queries (A, AAAA, PTR) against the
+// system resolver(s) or an explicit list of upstreams, independent of the
+// HTTP-based probes in the parent ipv6test package. Queries can carry an
+// EDNS0 OPT pseudo-RR (with the DNSSEC-OK bit) so callers can distinguish
+// plain lookups from ones that exercise larger UDP responses or DNSSEC.
+//
+// The wire format implemented here is intentionally small (RFC 1035 plus
+// the EDNS0 OPT RR from RFC 6891) so the package has no third-party
+// dependency.
+package dnsprobe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Type is a DNS RR type (RFC 1035 plus the EDNS0 OPT pseudo-type).
+type Type uint16
+
+const (
+	TypeA      Type = 1
+	TypePTR    Type = 12
+	TypeAAAA   Type = 28
+	TypeOPT    Type = 41
+	TypeRRSIG  Type = 46
+	TypeDNSKEY Type = 48
+	TypeHTTPS  Type = 65 // RFC 9460 SVCB/HTTPS
+)
+
+// Query describes a single DNS question plus optional EDNS0 options.
+type Query struct {
+	Name            string
+	Qtype           Type
+	EDNS0BufferSize uint16 // 0 disables the OPT pseudo-RR entirely
+	DNSSECOK        bool
+}
+
+// Result is what we learned from one query against one server.
+type Result struct {
+	Server      netip.AddrPort
+	Via         string // "v4" or "v6": transport the query went out on
+	RTT         time.Duration
+	RCODE       int
+	Flags       uint16
+	Truncated   bool
+	AnswerBytes int
+	Answers     []string // textual A/AAAA/PTR record data, in answer order
+	HasRRSIG    bool
+	HasDNSKEY   bool
+	HasHTTPS    bool // an HTTPS/SVCB record was present (RDATA itself is not parsed)
+}
+
+var errShortMessage = errors.New("dnsprobe: message too short")
+
+// Do sends req to server over UDP and parses the response.
+func Do(ctx context.Context, server netip.AddrPort, req Query) (Result, error) {
+	msg, err := encode(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server.String())
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	start := time.Now()
+	if _, err := conn.Write(msg); err != nil {
+		return Result{}, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	rtt := time.Since(start)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res, err := decode(buf[:n])
+	if err != nil {
+		return Result{}, err
+	}
+	res.RTT = rtt
+	res.Server = server
+	if server.Addr().Is4() || server.Addr().Is4In6() {
+		res.Via = "v4"
+	} else {
+		res.Via = "v6"
+	}
+	return res, nil
+}
+
+// Resolver identifies an upstream by transport: plain UDP/TCP, DNS-over-TLS
+// (DoT), or DNS-over-HTTPS (DoH).
+type Resolver struct {
+	Scheme string // "udp", "tcp", "tls", or "https"
+	Target string // host:port for udp/tcp/tls; the full URL for https
+}
+
+// String renders r back into the URI form ParseResolver accepts.
+func (r Resolver) String() string {
+	if r.Scheme == "https" {
+		return r.Target
+	}
+	return r.Scheme + "://" + r.Target
+}
+
+// ParseResolver parses a resolver URI: udp://host:port, tcp://host:port,
+// tls://host:port (DoT, default port 853), or https://host/path (DoH).
+// udp/tcp default to port 53 when the port is omitted.
+func ParseResolver(spec string) (Resolver, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return Resolver{}, fmt.Errorf("dnsprobe: invalid resolver spec %q", spec)
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		return Resolver{Scheme: u.Scheme, Target: hostPort(u.Host, "53")}, nil
+	case "tls":
+		return Resolver{Scheme: "tls", Target: hostPort(u.Host, "853")}, nil
+	case "https":
+		return Resolver{Scheme: "https", Target: spec}, nil
+	default:
+		return Resolver{}, fmt.Errorf("dnsprobe: unsupported resolver scheme %q", u.Scheme)
+	}
+}
+
+func hostPort(host, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, defaultPort)
+}
+
+// DoResolver sends req to resolver over whichever transport its Scheme
+// names, dispatching to Do (udp), doTCP, doTLS (DoT), or doHTTPS (DoH).
+func DoResolver(ctx context.Context, resolver Resolver, req Query) (Result, error) {
+	switch resolver.Scheme {
+	case "udp":
+		addr, err := netip.ParseAddrPort(resolver.Target)
+		if err != nil {
+			return Result{}, fmt.Errorf("dnsprobe: %w", err)
+		}
+		return Do(ctx, addr, req)
+	case "tcp":
+		return doTCP(ctx, resolver.Target, req)
+	case "tls":
+		return doTLS(ctx, resolver.Target, req)
+	case "https":
+		return doHTTPS(ctx, resolver.Target, req)
+	default:
+		return Result{}, fmt.Errorf("dnsprobe: unsupported resolver scheme %q", resolver.Scheme)
+	}
+}
+
+// doTCP sends req over a plain TCP connection using the RFC 1035 4.2.2
+// two-byte length prefix, used both for DoT and for retrying a UDP
+// response that came back truncated.
+func doTCP(ctx context.Context, target string, req Query) (Result, error) {
+	msg, err := encode(req)
+	if err != nil {
+		return Result{}, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	start := time.Now()
+	res, err := exchangeStream(conn, msg)
+	if err != nil {
+		return Result{}, err
+	}
+	res.RTT = time.Since(start)
+	res.Via = viaForAddr(conn.RemoteAddr())
+	return res, nil
+}
+
+// doTLS implements DNS-over-TLS (RFC 7858): the same length-prefixed wire
+// format as doTCP, carried over a crypto/tls connection instead of plain
+// TCP, so no third-party DoT client is needed.
+func doTLS(ctx context.Context, target string, req Query) (Result, error) {
+	msg, err := encode(req)
+	if err != nil {
+		return Result{}, err
+	}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		return Result{}, err
+	}
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return Result{}, err
+	}
+	defer raw.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = raw.SetDeadline(dl)
+	}
+	conn := tls.Client(raw, &tls.Config{ServerName: host})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	res, err := exchangeStream(conn, msg)
+	if err != nil {
+		return Result{}, err
+	}
+	res.RTT = time.Since(start)
+	res.Via = viaForAddr(raw.RemoteAddr())
+	return res, nil
+}
+
+func exchangeStream(conn net.Conn, msg []byte) (Result, error) {
+	var lenPrefix [2]byte
+	binary.BigEndian.PutUint16(lenPrefix[:], uint16(len(msg)))
+	if _, err := conn.Write(append(lenPrefix[:], msg...)); err != nil {
+		return Result{}, err
+	}
+	if _, err := io.ReadFull(conn, lenPrefix[:]); err != nil {
+		return Result{}, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(lenPrefix[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return Result{}, err
+	}
+	return decode(buf)
+}
+
+// doHTTPS implements DNS-over-HTTPS (RFC 8484) via a plain POST of the
+// wire-format query with the application/dns-message content type, so no
+// third-party DoH client is needed.
+func doHTTPS(ctx context.Context, target string, req Query) (Result, error) {
+	msg, err := encode(req)
+	if err != nil {
+		return Result{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(msg))
+	if err != nil {
+		return Result{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/dns-message")
+	httpReq.Header.Set("Accept", "application/dns-message")
+
+	var via string
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				conn, err := d.DialContext(ctx, network, addr)
+				if err == nil {
+					via = viaForAddr(conn.RemoteAddr())
+				}
+				return conn, err
+			},
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	rtt := time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("dnsprobe: doh status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := decode(body)
+	if err != nil {
+		return Result{}, err
+	}
+	res.RTT = rtt
+	res.Via = via
+	return res, nil
+}
+
+func viaForAddr(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	a, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	if a.Is4() || a.Is4In6() {
+		return "v4"
+	}
+	return "v6"
+}
+
+// SystemResolvers returns the nameserver entries from /etc/resolv.conf, or
+// nil if it cannot be read (e.g. on platforms without one).
+func SystemResolvers() []netip.AddrPort {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var out []netip.AddrPort
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		addr, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		out = append(out, netip.AddrPortFrom(addr, 53))
+	}
+	return out
+}
+
+func encode(q Query) ([]byte, error) {
+	var b []byte
+	// Header: ID, flags (RD=1), QDCOUNT=1, AN/NS/AR-COUNT.
+	b = append(b, 0xAB, 0xCD)
+	b = append(b, 0x01, 0x00) // flags: RD
+	b = append(b, 0x00, 0x01) // QDCOUNT
+	b = append(b, 0x00, 0x00) // ANCOUNT
+	b = append(b, 0x00, 0x00) // NSCOUNT
+	arcount := uint16(0)
+	if q.EDNS0BufferSize > 0 {
+		arcount = 1
+	}
+	b = append(b, byte(arcount>>8), byte(arcount))
+
+	name, err := encodeName(q.Name)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, name...)
+	b = append(b, byte(q.Qtype>>8), byte(q.Qtype))
+	b = append(b, 0x00, 0x01) // QCLASS IN
+
+	if q.EDNS0BufferSize > 0 {
+		b = append(b, 0x00)                                                // root name
+		b = append(b, byte(TypeOPT>>8), byte(TypeOPT))                     // TYPE=OPT
+		b = append(b, byte(q.EDNS0BufferSize>>8), byte(q.EDNS0BufferSize)) // CLASS=UDP size
+		var ttl uint32                                                     // extended RCODE(8) + version(8) + flags(16)
+		if q.DNSSECOK {
+			ttl = 1 << 15 // DO bit
+		}
+		var ttlBuf [4]byte
+		binary.BigEndian.PutUint32(ttlBuf[:], ttl)
+		b = append(b, ttlBuf[:]...)
+		b = append(b, 0x00, 0x00) // RDLEN=0
+	}
+	return b, nil
+}
+
+func encodeName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	var b []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) > 63 {
+				return nil, fmt.Errorf("dnsprobe: label %q too long", label)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	b = append(b, 0x00)
+	return b, nil
+}
+
+func decode(buf []byte) (Result, error) {
+	if len(buf) < 12 {
+		return Result{}, errShortMessage
+	}
+	flags := binary.BigEndian.Uint16(buf[2:4])
+	qdcount := binary.BigEndian.Uint16(buf[4:6])
+	ancount := binary.BigEndian.Uint16(buf[6:8])
+	nscount := binary.BigEndian.Uint16(buf[8:10])
+	arcount := binary.BigEndian.Uint16(buf[10:12])
+
+	res := Result{
+		Flags:       flags,
+		RCODE:       int(flags & 0x000F),
+		Truncated:   flags&0x0200 != 0,
+		AnswerBytes: len(buf),
+	}
+
+	off := 12
+	for i := 0; i < int(qdcount); i++ {
+		var err error
+		off, err = skipName(buf, off)
+		if err != nil {
+			return res, err
+		}
+		off += 4 // QTYPE+QCLASS
+	}
+
+	total := int(ancount) + int(nscount) + int(arcount)
+	for i := 0; i < total; i++ {
+		var rtype Type
+		var rdata []byte
+		var err error
+		off, rtype, rdata, err = readRR(buf, off)
+		if err != nil {
+			return res, err
+		}
+		switch rtype {
+		case TypeA:
+			if len(rdata) == 4 {
+				res.Answers = append(res.Answers, net.IP(rdata).String())
+			}
+		case TypeAAAA:
+			if len(rdata) == 16 {
+				res.Answers = append(res.Answers, net.IP(rdata).String())
+			}
+		case TypePTR:
+			if name, _, err := parseName(buf, off-len(rdata)); err == nil {
+				res.Answers = append(res.Answers, name)
+			}
+		case TypeRRSIG:
+			res.HasRRSIG = true
+		case TypeDNSKEY:
+			res.HasDNSKEY = true
+		case TypeHTTPS:
+			res.HasHTTPS = true
+		}
+	}
+	return res, nil
+}
+
+// skipName advances past a (possibly compressed) name and returns the new offset.
+func skipName(buf []byte, off int) (int, error) {
+	for {
+		if off >= len(buf) {
+			return 0, errShortMessage
+		}
+		l := int(buf[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(buf) {
+				return 0, errShortMessage
+			}
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+}
+
+// parseName decodes a (possibly compressed) name starting at off.
+func parseName(buf []byte, off int) (string, int, error) {
+	var labels []string
+	jumped := false
+	cur := off
+	next := off
+	for {
+		if cur >= len(buf) {
+			return "", 0, errShortMessage
+		}
+		l := int(buf[cur])
+		switch {
+		case l == 0:
+			if !jumped {
+				next = cur + 1
+			}
+			if len(labels) == 0 {
+				return ".", next, nil
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case l&0xC0 == 0xC0:
+			if cur+1 >= len(buf) {
+				return "", 0, errShortMessage
+			}
+			if !jumped {
+				next = cur + 2
+			}
+			cur = int(binary.BigEndian.Uint16(buf[cur:cur+2]) &^ 0xC000)
+			jumped = true
+		default:
+			if cur+1+l > len(buf) {
+				return "", 0, errShortMessage
+			}
+			labels = append(labels, string(buf[cur+1:cur+1+l]))
+			cur += 1 + l
+		}
+	}
+}
+
+// readRR parses one resource record at off, returning the new offset, its
+// type, and its raw RDATA.
+func readRR(buf []byte, off int) (int, Type, []byte, error) {
+	off, err := skipName(buf, off)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if off+10 > len(buf) {
+		return 0, 0, nil, errShortMessage
+	}
+	rtype := Type(binary.BigEndian.Uint16(buf[off : off+2]))
+	rdlen := int(binary.BigEndian.Uint16(buf[off+8 : off+10]))
+	off += 10
+	if off+rdlen > len(buf) {
+		return 0, 0, nil, errShortMessage
+	}
+	rdata := buf[off : off+rdlen]
+	return off + rdlen, rtype, rdata, nil
+}
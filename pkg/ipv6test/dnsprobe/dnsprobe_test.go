@@ -0,0 +1,250 @@
+package dnsprobe
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestEncodeName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []byte
+	}{
+		{"simple", "example.com", []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}},
+		{"trailing_dot", "example.com.", []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}},
+		{"root", "", []byte{0}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := encodeName(c.in)
+			if err != nil {
+				t.Fatalf("encodeName(%q) error: %v", c.in, err)
+			}
+			if string(got) != string(c.want) {
+				t.Fatalf("encodeName(%q) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEncodeNameLabelTooLong(t *testing.T) {
+	long := make([]byte, 64)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if _, err := encodeName(string(long) + ".com"); err == nil {
+		t.Fatal("expected an error for a 64-byte label")
+	}
+}
+
+func TestEncodeQuery(t *testing.T) {
+	msg, err := encode(Query{Name: "example.com", Qtype: TypeA})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if len(msg) < 12 {
+		t.Fatalf("encoded message too short: %d bytes", len(msg))
+	}
+	if flags := binary.BigEndian.Uint16(msg[2:4]); flags != 0x0100 {
+		t.Fatalf("flags = 0x%04x, want 0x0100 (RD)", flags)
+	}
+	if qdcount := binary.BigEndian.Uint16(msg[4:6]); qdcount != 1 {
+		t.Fatalf("qdcount = %d, want 1", qdcount)
+	}
+	if arcount := binary.BigEndian.Uint16(msg[10:12]); arcount != 0 {
+		t.Fatalf("arcount = %d, want 0 (no EDNS0 requested)", arcount)
+	}
+
+	qtype := binary.BigEndian.Uint16(msg[len(msg)-4 : len(msg)-2])
+	if Type(qtype) != TypeA {
+		t.Fatalf("qtype = %d, want %d", qtype, TypeA)
+	}
+}
+
+func TestEncodeQueryEDNS0(t *testing.T) {
+	msg, err := encode(Query{Name: "example.com", Qtype: TypeAAAA, EDNS0BufferSize: 4096, DNSSECOK: true})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if arcount := binary.BigEndian.Uint16(msg[10:12]); arcount != 1 {
+		t.Fatalf("arcount = %d, want 1 (OPT RR)", arcount)
+	}
+	// The OPT RR is the last 11 bytes: root name, TYPE, CLASS, TTL, RDLEN.
+	opt := msg[len(msg)-11:]
+	if opt[0] != 0x00 {
+		t.Fatalf("OPT owner name = %#v, want root (0x00)", opt[0])
+	}
+	if otype := binary.BigEndian.Uint16(opt[1:3]); Type(otype) != TypeOPT {
+		t.Fatalf("OPT type = %d, want %d", otype, TypeOPT)
+	}
+	if class := binary.BigEndian.Uint16(opt[3:5]); class != 4096 {
+		t.Fatalf("OPT class (UDP size) = %d, want 4096", class)
+	}
+	ttl := binary.BigEndian.Uint32(opt[5:9])
+	if ttl&(1<<15) == 0 {
+		t.Fatal("DO bit not set despite DNSSECOK: true")
+	}
+}
+
+// buildResponse hand-assembles a minimal DNS response: one question plus
+// the given answer RRs, so decode can be exercised without a live server.
+func buildResponse(t *testing.T, flags uint16, question []byte, answers [][]byte) []byte {
+	t.Helper()
+	var b []byte
+	b = append(b, 0xAB, 0xCD)
+	var flagBuf [2]byte
+	binary.BigEndian.PutUint16(flagBuf[:], flags)
+	b = append(b, flagBuf[:]...)
+	b = append(b, 0x00, 0x01) // QDCOUNT
+	var anBuf [2]byte
+	binary.BigEndian.PutUint16(anBuf[:], uint16(len(answers)))
+	b = append(b, anBuf[:]...)
+	b = append(b, 0x00, 0x00, 0x00, 0x00) // NSCOUNT, ARCOUNT
+	b = append(b, question...)
+	for _, a := range answers {
+		b = append(b, a...)
+	}
+	return b
+}
+
+func aQuestion(t *testing.T, name string, qtype Type) []byte {
+	t.Helper()
+	n, err := encodeName(name)
+	if err != nil {
+		t.Fatalf("encodeName: %v", err)
+	}
+	var tc [4]byte
+	binary.BigEndian.PutUint16(tc[0:2], uint16(qtype))
+	binary.BigEndian.PutUint16(tc[2:4], 1) // QCLASS IN
+	return append(n, tc[:]...)
+}
+
+// rr builds a resource record using a compression pointer back to the
+// question's name (offset 12) as its owner, mirroring what real resolvers
+// send back.
+func rr(rtype Type, rdata []byte) []byte {
+	var b []byte
+	b = append(b, 0xC0, 0x0C) // pointer to offset 12 (the question name)
+	var tc [8]byte
+	binary.BigEndian.PutUint16(tc[0:2], uint16(rtype))
+	binary.BigEndian.PutUint16(tc[2:4], 1) // CLASS IN
+	binary.BigEndian.PutUint32(tc[4:8], 300)
+	b = append(b, tc[:]...)
+	var rdlen [2]byte
+	binary.BigEndian.PutUint16(rdlen[:], uint16(len(rdata)))
+	b = append(b, rdlen[:]...)
+	b = append(b, rdata...)
+	return b
+}
+
+func TestDecodeARecord(t *testing.T) {
+	q := aQuestion(t, "example.com", TypeA)
+	buf := buildResponse(t, 0x8180, q, [][]byte{rr(TypeA, []byte{93, 184, 216, 34})})
+
+	res, err := decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.RCODE != 0 {
+		t.Fatalf("RCODE = %d, want 0", res.RCODE)
+	}
+	if res.Truncated {
+		t.Fatal("Truncated = true, want false")
+	}
+	if len(res.Answers) != 1 || res.Answers[0] != "93.184.216.34" {
+		t.Fatalf("Answers = %v, want [93.184.216.34]", res.Answers)
+	}
+}
+
+func TestDecodeAAAARecord(t *testing.T) {
+	q := aQuestion(t, "example.com", TypeAAAA)
+	addr := []byte{0x26, 0x06, 0x28, 0x00, 0x02, 0x20, 0x00, 0x01, 0x02, 0x48, 0x18, 0x93, 0x25, 0xc8, 0x19, 0x46}
+	buf := buildResponse(t, 0x8180, q, [][]byte{rr(TypeAAAA, addr)})
+
+	res, err := decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(res.Answers) != 1 || res.Answers[0] != "2606:2800:220:1:248:1893:25c8:1946" {
+		t.Fatalf("Answers = %v, want [2606:2800:220:1:248:1893:25c8:1946]", res.Answers)
+	}
+}
+
+func TestDecodeTruncatedFlag(t *testing.T) {
+	q := aQuestion(t, "example.com", TypeA)
+	buf := buildResponse(t, 0x8180|0x0200, q, nil)
+
+	res, err := decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !res.Truncated {
+		t.Fatal("Truncated = false, want true (TC bit set)")
+	}
+}
+
+func TestDecodeRcodeAndSentinelRecords(t *testing.T) {
+	q := aQuestion(t, "example.com", TypeA)
+	// RCODE=3 (NXDOMAIN), carrying RRSIG and DNSKEY records as sentinels.
+	buf := buildResponse(t, 0x8183, q, [][]byte{rr(TypeRRSIG, []byte{0x00}), rr(TypeDNSKEY, []byte{0x00})})
+
+	res, err := decode(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.RCODE != 3 {
+		t.Fatalf("RCODE = %d, want 3", res.RCODE)
+	}
+	if !res.HasRRSIG || !res.HasDNSKEY {
+		t.Fatalf("HasRRSIG = %v, HasDNSKEY = %v, want both true", res.HasRRSIG, res.HasDNSKEY)
+	}
+}
+
+func TestDecodeShortMessage(t *testing.T) {
+	if _, err := decode([]byte{0x00, 0x01, 0x02}); err == nil {
+		t.Fatal("expected an error decoding a message shorter than the header")
+	}
+}
+
+func TestParseResolver(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		want    Resolver
+		wantErr bool
+	}{
+		{"udp_with_port", "udp://9.9.9.9:53", Resolver{Scheme: "udp", Target: "9.9.9.9:53"}, false},
+		{"udp_default_port", "udp://9.9.9.9", Resolver{Scheme: "udp", Target: "9.9.9.9:53"}, false},
+		{"tls_default_port", "tls://dns.google", Resolver{Scheme: "tls", Target: "dns.google:853"}, false},
+		{"https_passthrough", "https://cloudflare-dns.com/dns-query", Resolver{Scheme: "https", Target: "https://cloudflare-dns.com/dns-query"}, false},
+		{"unsupported_scheme", "ftp://9.9.9.9", Resolver{}, true},
+		{"unparseable", "://bad", Resolver{}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseResolver(c.spec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("ParseResolver(%q) = %+v, want an error", c.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResolver(%q) error: %v", c.spec, err)
+			}
+			if got != c.want {
+				t.Fatalf("ParseResolver(%q) = %+v, want %+v", c.spec, got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolverString(t *testing.T) {
+	if got := (Resolver{Scheme: "udp", Target: "9.9.9.9:53"}).String(); got != "udp://9.9.9.9:53" {
+		t.Fatalf("String() = %q, want %q", got, "udp://9.9.9.9:53")
+	}
+	if got := (Resolver{Scheme: "https", Target: "https://cloudflare-dns.com/dns-query"}).String(); got != "https://cloudflare-dns.com/dns-query" {
+		t.Fatalf("String() = %q, want the raw URL unchanged", got)
+	}
+}
@@ -0,0 +1,222 @@
+package ipmeta
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+)
+
+// BGPRIB answers ASN lookups from a local MRT TABLE_DUMPV2 RIB dump (RFC
+// 6396) -- the format RouteViews/RIPE RIS route collectors publish, and
+// that "bgpdump"-style tools read.
+//
+// This is a hand-rolled reader rather than a binding to
+// github.com/osrg/gobgp: that library brings in a full BGP speaker (its
+// own gRPC API and protobuf toolchain) for what amounts to reading a
+// handful of TLV records, and this package already avoids third-party
+// wire-format dependencies (see pkg/ipv6test/dnsprobe's DNS parser for
+// the same rationale).
+//
+// Supported: PEER_INDEX_TABLE-less TABLE_DUMPV2 RIB_IPV4_UNICAST and
+// RIB_IPV6_UNICAST entries, reading the origin AS off the end of the
+// first parseable AS_PATH attribute (TABLE_DUMPV2 always encodes AS
+// numbers as 4 bytes, regardless of what the dumping router's peer
+// sessions negotiated). Not supported: the older TABLE_DUMP (v1) format,
+// and bzip2/gzip-compressed dumps -- callers must decompress first.
+type BGPRIB struct {
+	entries []ribEntry
+}
+
+type ribEntry struct {
+	prefix netip.Prefix
+	asn    int
+}
+
+const (
+	mrtTypeTableDumpV2        = 13
+	mrtSubtypeRIBIPv4Unicast  = 2
+	mrtSubtypeRIBIPv6Unicast  = 4
+	bgpAttrTypeASPath         = 2
+	bgpAttrFlagExtendedLength = 0x10
+)
+
+// NewBGPRIB loads and indexes the RIB dump at path.
+func NewBGPRIB(path string) (*BGPRIB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := parseTableDumpV2(f)
+	if err != nil {
+		return nil, err
+	}
+	return &BGPRIB{entries: entries}, nil
+}
+
+// Lookup implements Provider.
+func (b *BGPRIB) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	meta := classify(addr)
+	meta.Source = "bgp"
+	best, ok := b.longestMatch(addr)
+	if !ok {
+		return meta, fmt.Errorf("ipmeta: bgp: no RIB entry covers %s", addr)
+	}
+	meta.ASN = best.asn
+	meta.Prefix = best.prefix.String()
+	return meta, nil
+}
+
+func (b *BGPRIB) longestMatch(addr netip.Addr) (ribEntry, bool) {
+	var best ribEntry
+	found := false
+	for _, e := range b.entries {
+		if e.prefix.Contains(addr) && (!found || e.prefix.Bits() > best.prefix.Bits()) {
+			best, found = e, true
+		}
+	}
+	return best, found
+}
+
+// parseTableDumpV2 reads a stream of MRT records (RFC 6396 section 3:
+// 4-byte timestamp, 2-byte type, 2-byte subtype, 4-byte length, then that
+// many bytes of body), keeping only RIB_IPV4_UNICAST/RIB_IPV6_UNICAST
+// entries from TABLE_DUMPV2 records.
+func parseTableDumpV2(r io.Reader) ([]ribEntry, error) {
+	var entries []ribEntry
+	var hdr [12]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		mtype := binary.BigEndian.Uint16(hdr[4:6])
+		subtype := binary.BigEndian.Uint16(hdr[6:8])
+		length := binary.BigEndian.Uint32(hdr[8:12])
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		if mtype != mrtTypeTableDumpV2 {
+			continue
+		}
+		switch subtype {
+		case mrtSubtypeRIBIPv4Unicast:
+			if e, ok := parseRIBEntry(body, 4); ok {
+				entries = append(entries, e)
+			}
+		case mrtSubtypeRIBIPv6Unicast:
+			if e, ok := parseRIBEntry(body, 6); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// parseRIBEntry parses one RIB_IPV4_UNICAST/RIB_IPV6_UNICAST record body
+// (RFC 6396 section 4.3.2/4.3.4): sequence number, prefix length/bytes,
+// entry count, then one RIB entry per peer. It stops at the first entry
+// whose attributes yield an origin AS.
+func parseRIBEntry(body []byte, family int) (ribEntry, bool) {
+	if len(body) < 5 {
+		return ribEntry{}, false
+	}
+	off := 4 // sequence number
+	plen := int(body[off])
+	off++
+	nbytes := (plen + 7) / 8
+	if off+nbytes > len(body) {
+		return ribEntry{}, false
+	}
+	var addrBytes [16]byte
+	copy(addrBytes[:], body[off:off+nbytes])
+	off += nbytes
+
+	var prefix netip.Prefix
+	if family == 4 {
+		var a4 [4]byte
+		copy(a4[:], addrBytes[:4])
+		prefix = netip.PrefixFrom(netip.AddrFrom4(a4), plen)
+	} else {
+		prefix = netip.PrefixFrom(netip.AddrFrom16(addrBytes), plen)
+	}
+
+	if off+2 > len(body) {
+		return ribEntry{}, false
+	}
+	count := int(binary.BigEndian.Uint16(body[off : off+2]))
+	off += 2
+
+	for i := 0; i < count && off+8 <= len(body); i++ {
+		off += 6 // peer index (2) + originated time (4)
+		attrLen := int(binary.BigEndian.Uint16(body[off : off+2]))
+		off += 2
+		if off+attrLen > len(body) {
+			break
+		}
+		attrs := body[off : off+attrLen]
+		off += attrLen
+		if asn, ok := originASFromAttrs(attrs); ok {
+			return ribEntry{prefix: prefix, asn: asn}, true
+		}
+	}
+	return ribEntry{}, false
+}
+
+// originASFromAttrs scans a BGP path attribute list (RFC 4271 section
+// 4.3) for AS_PATH and returns the origin AS from it.
+func originASFromAttrs(b []byte) (int, bool) {
+	for len(b) >= 3 {
+		flags, typ := b[0], b[1]
+		var length, valOff int
+		if flags&bgpAttrFlagExtendedLength != 0 {
+			if len(b) < 4 {
+				return 0, false
+			}
+			length = int(binary.BigEndian.Uint16(b[2:4]))
+			valOff = 4
+		} else {
+			length = int(b[2])
+			valOff = 3
+		}
+		if valOff+length > len(b) {
+			return 0, false
+		}
+		value := b[valOff : valOff+length]
+		if typ == bgpAttrTypeASPath {
+			if asn, ok := originFromASPath(value); ok {
+				return asn, true
+			}
+		}
+		b = b[valOff+length:]
+	}
+	return 0, false
+}
+
+// originFromASPath returns the last AS number in the last path segment,
+// which is the origin AS (the path lists segments nearest-hop first).
+func originFromASPath(b []byte) (int, bool) {
+	var origin int
+	found := false
+	for len(b) >= 2 {
+		segLen := int(b[1])
+		b = b[2:]
+		need := segLen * 4
+		if len(b) < need {
+			return 0, false
+		}
+		for i := 0; i < segLen; i++ {
+			origin = int(binary.BigEndian.Uint32(b[i*4 : i*4+4]))
+			found = true
+		}
+		b = b[need:]
+	}
+	return origin, found
+}
@@ -0,0 +1,126 @@
+package ipmeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+// buildASPath encodes a single AS_SEQUENCE segment (RFC 4271 4.3) with one
+// 4-byte AS number per entry in asns, as TABLE_DUMPV2 always does
+// regardless of what the dumping router's peer sessions negotiated.
+func buildASPath(asns []int) []byte {
+	b := []byte{2, byte(len(asns))} // segment type AS_SEQUENCE, segment length
+	for _, asn := range asns {
+		var a [4]byte
+		binary.BigEndian.PutUint32(a[:], uint32(asn))
+		b = append(b, a[:]...)
+	}
+	return b
+}
+
+// buildPathAttr wraps an AS_PATH value in its attribute header (non-extended
+// length: flags, type, 1-byte length).
+func buildPathAttr(asPath []byte) []byte {
+	return append([]byte{0x40, bgpAttrTypeASPath, byte(len(asPath))}, asPath...)
+}
+
+// buildRIBEntryBody assembles one RIB_IPV4_UNICAST/RIB_IPV6_UNICAST record
+// body (RFC 6396 4.3.2/4.3.4) covering prefix, with a single RIB entry
+// carrying attrs.
+func buildRIBEntryBody(prefix netip.Prefix, attrs []byte) []byte {
+	var body []byte
+	body = append(body, 0, 0, 0, 0) // sequence number
+	plen := prefix.Bits()
+	body = append(body, byte(plen))
+	nbytes := (plen + 7) / 8
+	addr := prefix.Addr().AsSlice()
+	body = append(body, addr[:nbytes]...)
+	body = append(body, 0, 1)       // entry count = 1
+	body = append(body, 0, 0)       // peer index
+	body = append(body, 0, 0, 0, 0) // originated time
+	var attrLen [2]byte
+	binary.BigEndian.PutUint16(attrLen[:], uint16(len(attrs)))
+	body = append(body, attrLen[:]...)
+	body = append(body, attrs...)
+	return body
+}
+
+// buildMRTRecord wraps body in an MRT record header (RFC 6396 section 3).
+func buildMRTRecord(mtype, subtype uint16, body []byte) []byte {
+	var hdr [12]byte
+	binary.BigEndian.PutUint16(hdr[4:6], mtype)
+	binary.BigEndian.PutUint16(hdr[6:8], subtype)
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(len(body)))
+	return append(hdr[:], body...)
+}
+
+func TestParseTableDumpV2(t *testing.T) {
+	v4Prefix := netip.MustParsePrefix("203.0.113.0/24")
+	v6Prefix := netip.MustParsePrefix("2001:db8::/32")
+	v4Body := buildRIBEntryBody(v4Prefix, buildPathAttr(buildASPath([]int{64500, 64501})))
+	v6Body := buildRIBEntryBody(v6Prefix, buildPathAttr(buildASPath([]int{64502})))
+
+	var stream bytes.Buffer
+	stream.Write(buildMRTRecord(mrtTypeTableDumpV2, mrtSubtypeRIBIPv4Unicast, v4Body))
+	stream.Write(buildMRTRecord(mrtTypeTableDumpV2, mrtSubtypeRIBIPv6Unicast, v6Body))
+	stream.Write(buildMRTRecord(99, 1, []byte{0x01, 0x02, 0x03})) // unrelated MRT record type: skipped
+
+	entries, err := parseTableDumpV2(&stream)
+	if err != nil {
+		t.Fatalf("parseTableDumpV2: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	if entries[0].prefix != v4Prefix || entries[0].asn != 64501 {
+		t.Fatalf("entries[0] = %+v, want prefix %s asn 64501 (origin AS, last in path)", entries[0], v4Prefix)
+	}
+	if entries[1].prefix != v6Prefix || entries[1].asn != 64502 {
+		t.Fatalf("entries[1] = %+v, want prefix %s asn 64502", entries[1], v6Prefix)
+	}
+}
+
+func TestOriginFromASPath(t *testing.T) {
+	asn, ok := originFromASPath(buildASPath([]int{64500, 64501, 64502}))
+	if !ok {
+		t.Fatal("expected originFromASPath to find an origin AS")
+	}
+	if asn != 64502 {
+		t.Fatalf("origin AS = %d, want 64502 (last in the path, nearest-hop first)", asn)
+	}
+}
+
+func TestOriginFromASPathEmpty(t *testing.T) {
+	if _, ok := originFromASPath(nil); ok {
+		t.Fatal("expected no origin AS from an empty AS_PATH")
+	}
+}
+
+func TestBGPRIBLongestMatch(t *testing.T) {
+	b := &BGPRIB{entries: []ribEntry{
+		{prefix: netip.MustParsePrefix("203.0.113.0/24"), asn: 64500},
+		{prefix: netip.MustParsePrefix("203.0.113.128/25"), asn: 64501},
+	}}
+
+	got, ok := b.longestMatch(netip.MustParseAddr("203.0.113.200"))
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if got.asn != 64501 {
+		t.Fatalf("asn = %d, want 64501 (the more specific /25 covering this address)", got.asn)
+	}
+
+	got, ok = b.longestMatch(netip.MustParseAddr("203.0.113.50"))
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if got.asn != 64500 {
+		t.Fatalf("asn = %d, want 64500 (only the /24 covers this address)", got.asn)
+	}
+
+	if _, ok := b.longestMatch(netip.MustParseAddr("198.51.100.1")); ok {
+		t.Fatal("expected no match for an address outside every RIB prefix")
+	}
+}
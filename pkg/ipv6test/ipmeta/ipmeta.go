@@ -0,0 +1,215 @@
+// Package ipmeta enriches an observed IP address with ASN, organization,
+// and routing metadata from a pluggable backend, instead of hardcoding a
+// single local MaxMind GeoLite2-ASN database. Every backend answers the
+// same Provider interface; prefix-based classification (tunnel/ULA/
+// link-local detection) is independent of any backend and is applied
+// uniformly by Lookup before a provider's own findings are merged in.
+//
+// Built-in backends: MaxMind (the original local mmdb reader), RDAP (the
+// IANA bootstrap registry lookup), IRR (legacy whois route-object
+// lookup), and BGPRIB (a local MRT TABLE_DUMPV2 RIB dump). New takes a
+// comma-separated backend spec (e.g. "maxmind,rdap,bgp") and chains them,
+// first answer wins per field.
+package ipmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Meta is what we could determine about an observed address.
+type Meta struct {
+	ASN               int    `json:"asn,omitempty"`
+	ASNName           string `json:"asnName,omitempty"`
+	Org               string `json:"org,omitempty"`
+	Country           string `json:"country,omitempty"`
+	RegisteredCountry string `json:"registeredCountry,omitempty"`
+	Prefix            string `json:"prefix,omitempty"`
+	PTRName           string `json:"ptrName,omitempty"`
+	IsAnycast         bool   `json:"isAnycast,omitempty"`
+	IsTunnel          bool   `json:"isTunnel,omitempty"`
+	TunnelKind        string `json:"tunnelKind,omitempty"` // "6to4", "Teredo", "6rd"
+	IsULA             bool   `json:"isUla,omitempty"`
+	IsLinkLocal       bool   `json:"isLinkLocal,omitempty"`
+	Source            string `json:"source,omitempty"` // which backend(s) answered
+}
+
+// Provider looks up metadata for a single address.
+type Provider interface {
+	Lookup(ctx context.Context, addr netip.Addr) (Meta, error)
+}
+
+// Reloadable is implemented by providers that can reopen their backing
+// files at runtime, so Runner.ReloadGeo can pick up a refreshed database
+// (e.g. a cron-updated GeoLite2 file) without restarting the process. A
+// provider that doesn't support this (RDAP, IRR) simply doesn't implement
+// it; Chain reloads whichever of its members do.
+type Reloadable interface {
+	Reload() error
+}
+
+// Config carries the settings every built-in backend might need. Fields
+// irrelevant to the backends actually selected via New are ignored.
+type Config struct {
+	MaxMindDBPath        string       // maxmind: path to a GeoLite2-ASN/GeoIP2-ISP mmdb (required)
+	MaxMindCountryDBPath string       // maxmind: path to a GeoLite2-Country/GeoIP2-Country mmdb (optional)
+	MaxMindISPDBPath     string       // maxmind: path to a GeoIP2-ISP mmdb, overrides Org with the ISP record's (optional)
+	RDAPBaseURL          string       // rdap: bootstrap redirector base URL, default "https://rdap.org"
+	IRRHost              string       // irr: "host:port" of a whois server, default "whois.radb.net:43"
+	BGPRIBPath           string       // bgp: local MRT TABLE_DUMPV2 RIB dump file
+	HTTPClient           *http.Client // rdap: shared client; a default is used if nil
+}
+
+// New builds a Provider from a comma-separated backend spec such as
+// "maxmind,rdap,bgp". A single backend is returned directly; more than
+// one is combined into a Chain where the first backend to answer a given
+// field wins.
+func New(spec string, cfg Config) (Provider, error) {
+	var providers []Provider
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		p, err := newBackend(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	switch len(providers) {
+	case 0:
+		return nil, errors.New("ipmeta: no backend specified")
+	case 1:
+		return providers[0], nil
+	default:
+		return Chain{Providers: providers}, nil
+	}
+}
+
+func newBackend(name string, cfg Config) (Provider, error) {
+	switch name {
+	case "maxmind":
+		return NewMaxMind(cfg.MaxMindDBPath, cfg.MaxMindCountryDBPath, cfg.MaxMindISPDBPath)
+	case "rdap":
+		return NewRDAP(cfg.RDAPBaseURL, cfg.HTTPClient), nil
+	case "irr":
+		return NewIRR(cfg.IRRHost), nil
+	case "bgp":
+		return NewBGPRIB(cfg.BGPRIBPath)
+	default:
+		return nil, fmt.Errorf("ipmeta: unknown backend %q", name)
+	}
+}
+
+// Chain queries each Provider in order and merges their answers: the
+// first provider to set a given field wins. Classification fields
+// (IsULA, IsLinkLocal, and the 2002::/16 / 2001::/32 tunnel detection)
+// are computed once up front rather than per-backend.
+type Chain struct {
+	Providers []Provider
+}
+
+// Lookup implements Provider.
+func (c Chain) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	meta := classify(addr)
+	var sources []string
+	var lastErr error
+	for _, p := range c.Providers {
+		pm, err := p.Lookup(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if pm.Source != "" {
+			sources = append(sources, pm.Source)
+		}
+		mergeInto(&meta, pm)
+	}
+	meta.Source = strings.Join(sources, "+")
+	if len(sources) == 0 && lastErr != nil {
+		return meta, lastErr
+	}
+	return meta, nil
+}
+
+// Reload implements Reloadable by reloading every member Provider that
+// itself supports it, collecting every failure instead of stopping at
+// the first one so a single broken backend doesn't block the others.
+func (c Chain) Reload() error {
+	var errs []error
+	for _, p := range c.Providers {
+		if r, ok := p.(Reloadable); ok {
+			if err := r.Reload(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mergeInto copies any field set on src that dst doesn't already have.
+func mergeInto(dst *Meta, src Meta) {
+	if dst.ASN == 0 {
+		dst.ASN = src.ASN
+	}
+	if dst.ASNName == "" {
+		dst.ASNName = src.ASNName
+	}
+	if dst.Org == "" {
+		dst.Org = src.Org
+	}
+	if dst.Country == "" {
+		dst.Country = src.Country
+	}
+	if dst.RegisteredCountry == "" {
+		dst.RegisteredCountry = src.RegisteredCountry
+	}
+	if dst.Prefix == "" {
+		dst.Prefix = src.Prefix
+	}
+	if dst.PTRName == "" {
+		dst.PTRName = src.PTRName
+	}
+	if src.IsAnycast {
+		dst.IsAnycast = true
+	}
+	if !dst.IsTunnel && src.IsTunnel {
+		dst.IsTunnel, dst.TunnelKind = true, src.TunnelKind
+	}
+}
+
+// RFC 6724 prefixes used to classify an address independent of any
+// backend: 6to4 relay (2002::/16), Teredo (2001::/32), unique local
+// (fc00::/7, RFC 4193), and link-local (fe80::/10).
+var (
+	prefix6to4      = netip.MustParsePrefix("2002::/16")
+	prefixTeredo    = netip.MustParsePrefix("2001::/32")
+	prefixULA       = netip.MustParsePrefix("fc00::/7")
+	prefixLinkLocal = netip.MustParsePrefix("fe80::/10")
+)
+
+// classify derives the backend-independent fields of Meta from addr
+// alone. 6rd has no fixed prefix (it's delegated per-ISP from their own
+// space), so it can't be detected this way -- backends that have organization
+// data may still set IsTunnel/TunnelKind="6rd" themselves when they
+// recognize it.
+func classify(addr netip.Addr) Meta {
+	var m Meta
+	if !addr.Is6() || addr.Is4In6() {
+		return m
+	}
+	switch {
+	case prefix6to4.Contains(addr):
+		m.IsTunnel, m.TunnelKind = true, "6to4"
+	case prefixTeredo.Contains(addr):
+		m.IsTunnel, m.TunnelKind = true, "Teredo"
+	}
+	m.IsULA = prefixULA.Contains(addr)
+	m.IsLinkLocal = prefixLinkLocal.Contains(addr)
+	return m
+}
@@ -0,0 +1,152 @@
+package ipmeta
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name string
+		addr netip.Addr
+		want Meta
+	}{
+		{"6to4", netip.MustParseAddr("2002:c000:0204::1"), Meta{IsTunnel: true, TunnelKind: "6to4"}},
+		{"teredo", netip.MustParseAddr("2001:0:4136:e378::1"), Meta{IsTunnel: true, TunnelKind: "Teredo"}},
+		{"ula", netip.MustParseAddr("fc00::1"), Meta{IsULA: true}},
+		{"link_local", netip.MustParseAddr("fe80::1"), Meta{IsLinkLocal: true}},
+		{"ordinary_v6", netip.MustParseAddr("2606:4700:4700::1111"), Meta{}},
+		{"v4_mapped_not_classified", netip.MustParseAddr("::ffff:192.0.2.1"), Meta{}},
+		{"plain_v4_not_classified", netip.MustParseAddr("192.0.2.1"), Meta{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := classify(c.addr); got != c.want {
+				t.Fatalf("classify(%s) = %+v, want %+v", c.addr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMergeIntoFirstWins(t *testing.T) {
+	dst := Meta{ASN: 64500, Country: "US"}
+	mergeInto(&dst, Meta{ASN: 64501, Country: "CA", RegisteredCountry: "CA", Org: "Example Net"})
+
+	if dst.ASN != 64500 {
+		t.Fatalf("ASN = %d, want 64500 (dst already set, src should not override)", dst.ASN)
+	}
+	if dst.Country != "US" {
+		t.Fatalf("Country = %q, want %q (dst already set)", dst.Country, "US")
+	}
+	if dst.RegisteredCountry != "CA" {
+		t.Fatalf("RegisteredCountry = %q, want %q (unset on dst, filled from src)", dst.RegisteredCountry, "CA")
+	}
+	if dst.Org != "Example Net" {
+		t.Fatalf("Org = %q, want %q (unset on dst, filled from src)", dst.Org, "Example Net")
+	}
+}
+
+func TestMergeIntoAnycastAndTunnelSticky(t *testing.T) {
+	dst := Meta{IsTunnel: true, TunnelKind: "6to4"}
+	mergeInto(&dst, Meta{IsAnycast: true, IsTunnel: true, TunnelKind: "Teredo"})
+
+	if !dst.IsAnycast {
+		t.Fatal("IsAnycast should become true once any provider reports it")
+	}
+	if dst.TunnelKind != "6to4" {
+		t.Fatalf("TunnelKind = %q, want %q (dst's tunnel classification should not be overwritten)", dst.TunnelKind, "6to4")
+	}
+}
+
+// fakeProvider is a Provider stub for Chain tests, avoiding the need for a
+// real mmdb file or network access.
+type fakeProvider struct {
+	meta Meta
+	err  error
+}
+
+func (f fakeProvider) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	return f.meta, f.err
+}
+
+func TestChainLookupMergesAndJoinsSources(t *testing.T) {
+	c := Chain{Providers: []Provider{
+		fakeProvider{meta: Meta{Source: "maxmind", ASN: 64500, Org: "Example Net"}},
+		fakeProvider{meta: Meta{Source: "rdap", Org: "Should Not Win", RegisteredCountry: "US"}},
+	}}
+
+	got, err := c.Lookup(context.Background(), netip.MustParseAddr("2606:4700:4700::1111"))
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if got.ASN != 64500 {
+		t.Fatalf("ASN = %d, want 64500", got.ASN)
+	}
+	if got.Org != "Example Net" {
+		t.Fatalf("Org = %q, want %q (first provider's answer wins)", got.Org, "Example Net")
+	}
+	if got.RegisteredCountry != "US" {
+		t.Fatalf("RegisteredCountry = %q, want %q (only the second provider set it)", got.RegisteredCountry, "US")
+	}
+	if got.Source != "maxmind+rdap" {
+		t.Fatalf("Source = %q, want %q", got.Source, "maxmind+rdap")
+	}
+}
+
+func TestChainLookupAllProvidersFail(t *testing.T) {
+	wantErr := errors.New("lookup failed")
+	c := Chain{Providers: []Provider{
+		fakeProvider{err: wantErr},
+		fakeProvider{err: wantErr},
+	}}
+
+	_, err := c.Lookup(context.Background(), netip.MustParseAddr("2606:4700:4700::1111"))
+	if err == nil {
+		t.Fatal("expected an error when every provider fails")
+	}
+}
+
+func TestChainLookupPartialFailureStillSucceeds(t *testing.T) {
+	c := Chain{Providers: []Provider{
+		fakeProvider{err: errors.New("rdap unreachable")},
+		fakeProvider{meta: Meta{Source: "bgp", ASN: 64502}},
+	}}
+
+	got, err := c.Lookup(context.Background(), netip.MustParseAddr("2606:4700:4700::1111"))
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if got.ASN != 64502 {
+		t.Fatalf("ASN = %d, want 64502 (from the provider that succeeded)", got.ASN)
+	}
+	if got.Source != "bgp" {
+		t.Fatalf("Source = %q, want %q", got.Source, "bgp")
+	}
+}
+
+type reloadableFake struct {
+	fakeProvider
+	reloadErr error
+	reloaded  bool
+}
+
+func (r *reloadableFake) Reload() error {
+	r.reloaded = true
+	return r.reloadErr
+}
+
+func TestChainReloadCollectsAllFailures(t *testing.T) {
+	ok := &reloadableFake{}
+	failing := &reloadableFake{reloadErr: errors.New("reopen failed")}
+	c := Chain{Providers: []Provider{ok, failing, fakeProvider{}}}
+
+	err := c.Reload()
+	if err == nil {
+		t.Fatal("expected an error since one member failed to reload")
+	}
+	if !ok.reloaded || !failing.reloaded {
+		t.Fatal("every Reloadable member should have been reloaded")
+	}
+}
@@ -0,0 +1,83 @@
+package ipmeta
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultIRRHost is RADb's public whois server, one of the most widely
+// mirrored Internet Routing Registries.
+const defaultIRRHost = "whois.radb.net:43"
+
+// IRR resolves the origin ASN (and a short description) of an address by
+// querying an Internet Routing Registry's legacy whois protocol (RFC
+// 3912, port 43) for the route/route6 object covering it -- the same
+// mechanism looking-glass tools have used for decades, answering from
+// registry data rather than live BGP.
+type IRR struct {
+	host        string // "host:port"
+	dialTimeout time.Duration
+}
+
+// NewIRR returns an IRR backend querying host (default defaultIRRHost).
+func NewIRR(host string) *IRR {
+	if host == "" {
+		host = defaultIRRHost
+	}
+	return &IRR{host: host, dialTimeout: 10 * time.Second}
+}
+
+// Lookup implements Provider.
+func (p *IRR) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	meta := classify(addr)
+	meta.Source = "irr"
+
+	var d net.Dialer
+	d.Timeout = p.dialTimeout
+	conn, err := d.DialContext(ctx, "tcp", p.host)
+	if err != nil {
+		return meta, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s\r\n", addr.String()); err != nil {
+		return meta, err
+	}
+
+	var origin, descr string
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "origin:"):
+			origin = strings.TrimSpace(strings.TrimPrefix(line, "origin:"))
+		case descr == "" && strings.HasPrefix(line, "descr:"):
+			descr = strings.TrimSpace(strings.TrimPrefix(line, "descr:"))
+		case descr == "" && strings.HasPrefix(line, "netname:"):
+			descr = strings.TrimSpace(strings.TrimPrefix(line, "netname:"))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return meta, err
+	}
+	if origin == "" {
+		return meta, fmt.Errorf("ipmeta: irr: no route object found for %s", addr)
+	}
+
+	asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(origin), "AS"))
+	if err != nil {
+		return meta, fmt.Errorf("ipmeta: irr: malformed origin %q: %w", origin, err)
+	}
+	meta.ASN = asn
+	meta.Org = descr
+	return meta, nil
+}
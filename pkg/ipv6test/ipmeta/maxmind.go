@@ -0,0 +1,111 @@
+package ipmeta
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// MaxMind answers ASN/organization/country lookups from local GeoLite2 (or
+// commercial GeoIP2) mmdb files via github.com/oschwald/geoip2-golang --
+// the dependency testipv6-server already used directly before this
+// package existed. The ASN database is required; the Country and ISP
+// databases are optional and simply leave the fields they'd have answered
+// unset when not configured.
+type MaxMind struct {
+	asnPath     string
+	countryPath string
+	ispPath     string
+
+	mu        sync.RWMutex
+	asnDB     *geoip2.Reader
+	countryDB *geoip2.Reader
+	ispDB     *geoip2.Reader
+}
+
+// NewMaxMind opens asnPath as a GeoLite2-ASN/GeoIP2-ISP mmdb, plus
+// countryPath and ispPath when non-empty. It fails if asnPath can't be
+// opened; a bad countryPath/ispPath also fails the call rather than
+// silently degrading, since those were explicitly configured.
+func NewMaxMind(asnPath, countryPath, ispPath string) (*MaxMind, error) {
+	m := &MaxMind{asnPath: asnPath, countryPath: countryPath, ispPath: ispPath}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload implements Reloadable: it reopens every configured mmdb file and
+// swaps them in, so a cron-refreshed GeoLite2 database is picked up
+// without restarting the process. On any open failure it leaves the
+// existing readers in place and returns the error.
+func (m *MaxMind) Reload() error {
+	asnDB, err := geoip2.Open(m.asnPath)
+	if err != nil {
+		return err
+	}
+	var countryDB, ispDB *geoip2.Reader
+	if m.countryPath != "" {
+		if countryDB, err = geoip2.Open(m.countryPath); err != nil {
+			asnDB.Close()
+			return err
+		}
+	}
+	if m.ispPath != "" {
+		if ispDB, err = geoip2.Open(m.ispPath); err != nil {
+			asnDB.Close()
+			if countryDB != nil {
+				countryDB.Close()
+			}
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	oldASN, oldCountry, oldISP := m.asnDB, m.countryDB, m.ispDB
+	m.asnDB, m.countryDB, m.ispDB = asnDB, countryDB, ispDB
+	m.mu.Unlock()
+
+	for _, db := range []*geoip2.Reader{oldASN, oldCountry, oldISP} {
+		if db != nil {
+			db.Close()
+		}
+	}
+	return nil
+}
+
+// Lookup implements Provider.
+func (m *MaxMind) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	meta := classify(addr)
+	meta.Source = "maxmind"
+
+	m.mu.RLock()
+	asnDB, countryDB, ispDB := m.asnDB, m.countryDB, m.ispDB
+	m.mu.RUnlock()
+
+	rec, err := asnDB.ASN(addr.AsSlice())
+	if err != nil {
+		return meta, err
+	}
+	meta.ASN = int(rec.AutonomousSystemNumber)
+	meta.ASNName = rec.AutonomousSystemOrganization
+	meta.Org = rec.AutonomousSystemOrganization
+
+	if ispDB != nil {
+		if isp, err := ispDB.ISP(addr.AsSlice()); err == nil && isp.Organization != "" {
+			meta.Org = isp.Organization
+		}
+	}
+	if countryDB != nil {
+		if c, err := countryDB.Country(addr.AsSlice()); err == nil {
+			meta.Country = c.Country.IsoCode
+			meta.RegisteredCountry = c.RegisteredCountry.IsoCode
+			if c.Traits.IsAnycast {
+				meta.IsAnycast = true
+			}
+		}
+	}
+	return meta, nil
+}
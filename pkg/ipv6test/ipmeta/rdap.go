@@ -0,0 +1,132 @@
+package ipmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// defaultRDAPBaseURL is IANA's generic RDAP bootstrap redirector: it 302s
+// an /ip/<addr> request to whichever RIR (RIPE/ARIN/APNIC/LACNIC/AFRINIC)
+// actually holds the address, so callers don't need their own bootstrap
+// registry file.
+const defaultRDAPBaseURL = "https://rdap.org"
+
+// RDAP resolves organization/country/registration data via RDAP (RFC
+// 7480-7484). Note that an IP network's RDAP record describes who it was
+// registered to, not who originates it in BGP today -- it does not carry
+// an origin ASN. Pair RDAP with the irr or bgp backend (via New's
+// comma-separated spec) to get both.
+type RDAP struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRDAP returns an RDAP backend querying baseURL (default
+// defaultRDAPBaseURL) with client (a default 10s-timeout client is used
+// if nil).
+func NewRDAP(baseURL string, client *http.Client) *RDAP {
+	if baseURL == "" {
+		baseURL = defaultRDAPBaseURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RDAP{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+type rdapIPResponse struct {
+	Name     string       `json:"name"`
+	Country  string       `json:"country"`
+	Entities []rdapEntity `json:"entities"`
+	Cidr0    []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"`
+}
+
+type rdapEntity struct {
+	Roles      []string        `json:"roles"`
+	VcardArray json.RawMessage `json:"vcardArray"`
+}
+
+// Lookup implements Provider.
+func (p *RDAP) Lookup(ctx context.Context, addr netip.Addr) (Meta, error) {
+	meta := classify(addr)
+	meta.Source = "rdap"
+
+	url := fmt.Sprintf("%s/ip/%s", p.baseURL, addr.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return meta, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return meta, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("ipmeta: rdap %s: status %d", url, resp.StatusCode)
+	}
+
+	var body rdapIPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return meta, err
+	}
+	meta.Country = body.Country
+	meta.Org = firstNonEmpty(vcardFN(body.Entities), body.Name)
+	if len(body.Cidr0) > 0 {
+		c := body.Cidr0[0]
+		if c.V6Prefix != "" {
+			meta.Prefix = fmt.Sprintf("%s/%d", c.V6Prefix, c.Length)
+		} else if c.V4Prefix != "" {
+			meta.Prefix = fmt.Sprintf("%s/%d", c.V4Prefix, c.Length)
+		}
+	}
+	return meta, nil
+}
+
+// vcardFN extracts the first "fn" (formatted name) field out of the
+// jCard vcardArray of any entity, which RDAP uses for the org/contact
+// name instead of a plain string field.
+func vcardFN(entities []rdapEntity) string {
+	for _, e := range entities {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(e.VcardArray, &arr); err != nil || len(arr) < 2 {
+			continue
+		}
+		var fields [][]any
+		if err := json.Unmarshal(arr[1], &fields); err != nil {
+			continue
+		}
+		for _, f := range fields {
+			if len(f) < 4 {
+				continue
+			}
+			name, ok := f[0].(string)
+			if !ok || name != "fn" {
+				continue
+			}
+			if v, ok := f[3].(string); ok && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,456 @@
+// Package netcheck implements a minimal STUN (RFC 5389) client used to
+// classify NAT behavior and IPv6 reachability independent of DNS/HTTP:
+// the external address a STUN server observes, whether that mapping
+// changes across servers (a proxy for address-dependent/"symmetric" NAT),
+// whether the NAT hairpins traffic sent to your own external address back
+// to yourself, and whether the local host has only link-local/ULA IPv6
+// despite having an interface address at all. It implements just the
+// 20-byte STUN header (magic cookie 0x2112A442) and the
+// (XOR-)MAPPED-ADDRESS attributes, so no third-party STUN library is
+// required.
+package netcheck
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+const (
+	magicCookie     uint32 = 0x2112A442
+	bindingRequest  uint16 = 0x0001
+	bindingResponse uint16 = 0x0101
+	bindingError    uint16 = 0x0111
+
+	attrMappedAddress uint16 = 0x0001
+	attrXORMappedAddr uint16 = 0x0020
+	attrPadding       uint16 = 0x8026 // comprehension-optional; used only to inflate request size
+
+	familyIPv4 byte = 0x01
+	familyIPv6 byte = 0x02
+)
+
+var errShortAttr = errors.New("netcheck: STUN attribute too short")
+
+var ulaPrefix = netip.MustParsePrefix("fc00::/7")
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// paddingSizes are the request PADDING sizes probeMaxPadding tries, in
+// order, stopping at the first one that doesn't get an answer.
+var paddingSizes = []int{0, 256, 512, 900, 1200}
+
+// Report is the aggregate outcome of one netcheck pass for a single
+// address family.
+type Report struct {
+	Family         string         // "ipv4" or "ipv6"
+	Mapped         netip.AddrPort // external address:port as seen by the first responding STUN server
+	NATType        string         // "endpoint_independent", "address_dependent", or "unknown" (fewer than two servers responded)
+	HairpinTested  bool
+	HairpinWorks   bool
+	MaxSTUNPadding int  // largest padded binding request that still got an answer
+	CGNAT          bool // ipv4 only: local address falls in the RFC 6598 100.64.0.0/10 range
+	LinkLocalOnly  bool // ipv6 only: no global IPv6 address on any local interface, only link-local
+	ULAOnly        bool // ipv6 only: no global IPv6 address on any local interface, only ULA
+	Err            string
+}
+
+// Check probes servers (each "host:port") over family ("ip4" or "ip6")
+// and returns a Report. NATType requires at least two distinct servers to
+// classify; with fewer, it's reported as "unknown". All servers are probed
+// from a single local UDP socket (see bindOnConn) so a differing mapped
+// address reflects the NAT's actual per-destination behavior rather than a
+// new ephemeral source port the OS happened to pick for a fresh socket.
+func Check(ctx context.Context, family string, servers []string) Report {
+	network, famName := "udp4", "ipv4"
+	if family == "ip6" {
+		network, famName = "udp6", "ipv6"
+	}
+	rep := Report{Family: famName, NATType: "unknown"}
+	if len(servers) == 0 {
+		rep.Err = "no STUN servers configured"
+		return rep
+	}
+
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		rep.Err = err.Error()
+		return rep
+	}
+	defer conn.Close()
+
+	var mapped []netip.AddrPort
+	for _, s := range servers {
+		res, err := bindOnConn(ctx, conn, network, s)
+		if err != nil {
+			continue
+		}
+		mapped = append(mapped, res.Mapped)
+	}
+	if len(mapped) == 0 {
+		rep.Err = "no STUN server responded"
+		return rep
+	}
+	rep.Mapped = mapped[0]
+	if len(mapped) >= 2 {
+		rep.NATType = "endpoint_independent"
+		for _, m := range mapped[1:] {
+			if m != mapped[0] {
+				rep.NATType = "address_dependent"
+				break
+			}
+		}
+	}
+
+	rep.HairpinTested, rep.HairpinWorks = testHairpin(ctx, network, servers[0])
+	rep.MaxSTUNPadding = probeMaxPadding(ctx, network, servers[0])
+
+	if famName == "ipv4" {
+		rep.CGNAT = cgnatPrefix.Contains(localIPv4())
+	} else {
+		hasGlobal, hasULA, hasLinkLocal := classifyLocalIPv6()
+		rep.LinkLocalOnly = !hasGlobal && !hasULA && hasLinkLocal
+		rep.ULAOnly = !hasGlobal && hasULA
+	}
+	return rep
+}
+
+// Result is one STUN binding exchange's outcome.
+type Result struct {
+	Server string
+	Mapped netip.AddrPort
+	RTT    time.Duration
+}
+
+// bindOnce sends a single Binding Request to server over network ("udp4"
+// or "udp6"), optionally inflated with a PADDING attribute of padBytes,
+// and returns the mapped address it reports.
+func bindOnce(ctx context.Context, network, server string, padBytes int) (Result, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	txID := make([]byte, 12)
+	_, _ = rand.Read(txID)
+	msg := encodeBindingRequest(txID, padBytes)
+
+	start := time.Now()
+	if _, err := conn.Write(msg); err != nil {
+		return Result{}, err
+	}
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	rtt := time.Since(start)
+	if err != nil {
+		return Result{}, err
+	}
+	mapped, err := decodeBindingResponse(buf[:n], txID)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Server: server, Mapped: mapped, RTT: rtt}, nil
+}
+
+// bindOnConn sends a single Binding Request to server using an already-bound
+// local UDP socket, the way testHairpin's two sockets are used below, so a
+// caller probing several servers in turn (see Check's NAT-type loop) keeps
+// the same local port across all of them instead of a new one per server.
+func bindOnConn(ctx context.Context, conn *net.UDPConn, network, server string) (Result, error) {
+	serverAddr, err := net.ResolveUDPAddr(network, server)
+	if err != nil {
+		return Result{}, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	txID := make([]byte, 12)
+	_, _ = rand.Read(txID)
+	msg := encodeBindingRequest(txID, 0)
+
+	start := time.Now()
+	if _, err := conn.WriteToUDP(msg, serverAddr); err != nil {
+		return Result{}, err
+	}
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFromUDP(buf)
+	rtt := time.Since(start)
+	if err != nil {
+		return Result{}, err
+	}
+	mapped, err := decodeBindingResponse(buf[:n], txID)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Server: server, Mapped: mapped, RTT: rtt}, nil
+}
+
+// testHairpin checks whether packets sent to the NAT's own external
+// mapped address loop back to the local host: it binds two local UDP
+// sockets, learns socket A's external mapping via server, then has
+// socket B send a probe to that external address and checks whether A
+// receives it.
+func testHairpin(ctx context.Context, network, server string) (tested, works bool) {
+	connA, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return false, false
+	}
+	defer connA.Close()
+
+	serverAddr, err := net.ResolveUDPAddr(network, server)
+	if err != nil {
+		return false, false
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = connA.SetDeadline(dl)
+	}
+
+	txID := make([]byte, 12)
+	_, _ = rand.Read(txID)
+	if _, err := connA.WriteToUDP(encodeBindingRequest(txID, 0), serverAddr); err != nil {
+		return false, false
+	}
+	_ = connA.SetReadDeadline(time.Now().Add(3 * time.Second))
+	buf := make([]byte, 1500)
+	n, _, err := connA.ReadFromUDP(buf)
+	if err != nil {
+		return false, false
+	}
+	mapped, err := decodeBindingResponse(buf[:n], txID)
+	if err != nil {
+		return false, false
+	}
+
+	connB, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return true, false
+	}
+	defer connB.Close()
+
+	probe := []byte("netcheck-hairpin-probe")
+	if _, err := connB.WriteToUDP(probe, net.UDPAddrFromAddrPort(mapped)); err != nil {
+		return true, false
+	}
+
+	_ = connA.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err = connA.ReadFromUDP(buf)
+	if err != nil {
+		return true, false
+	}
+	return true, string(buf[:n]) == string(probe)
+}
+
+// probeMaxPadding sends binding requests padded with an increasingly
+// large PADDING attribute, as a rough stand-in for MTU discovery via
+// STUN message size (RFC 5389 has no dedicated MTU attribute): the
+// largest size that still gets an answer is a lower bound on what the
+// path between here and server can carry.
+func probeMaxPadding(ctx context.Context, network, server string) int {
+	max := 0
+	for _, size := range paddingSizes {
+		if _, err := bindOnce(ctx, network, server, size); err != nil {
+			break
+		}
+		max = size
+	}
+	return max
+}
+
+func encodeBindingRequest(txID []byte, padBytes int) []byte {
+	var attrs []byte
+	if padBytes > 0 {
+		attrs = append(attrs, encodeAttr(attrPadding, make([]byte, padBytes))...)
+	}
+	var hdr [20]byte
+	binary.BigEndian.PutUint16(hdr[0:2], bindingRequest)
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(attrs)))
+	binary.BigEndian.PutUint32(hdr[4:8], magicCookie)
+	copy(hdr[8:20], txID)
+	return append(hdr[:], attrs...)
+}
+
+func encodeAttr(t uint16, value []byte) []byte {
+	var th [4]byte
+	binary.BigEndian.PutUint16(th[0:2], t)
+	binary.BigEndian.PutUint16(th[2:4], uint16(len(value)))
+	b := append(th[:], value...)
+	if pad := (4 - len(value)%4) % 4; pad > 0 {
+		b = append(b, make([]byte, pad)...)
+	}
+	return b
+}
+
+func decodeBindingResponse(buf, txID []byte) (netip.AddrPort, error) {
+	if len(buf) < 20 {
+		return netip.AddrPort{}, errors.New("netcheck: short STUN message")
+	}
+	mtype := binary.BigEndian.Uint16(buf[0:2])
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	cookie := binary.BigEndian.Uint32(buf[4:8])
+	if cookie != magicCookie {
+		return netip.AddrPort{}, errors.New("netcheck: bad magic cookie")
+	}
+	if string(buf[8:20]) != string(txID) {
+		return netip.AddrPort{}, errors.New("netcheck: transaction ID mismatch")
+	}
+	if mtype == bindingError {
+		return netip.AddrPort{}, errors.New("netcheck: server returned a binding error")
+	}
+	if mtype != bindingResponse {
+		return netip.AddrPort{}, fmt.Errorf("netcheck: unexpected message type 0x%04x", mtype)
+	}
+	if 20+length > len(buf) {
+		length = len(buf) - 20
+	}
+
+	off, end := 20, 20+length
+	var xor, plain netip.AddrPort
+	for off+4 <= end {
+		atype := binary.BigEndian.Uint16(buf[off : off+2])
+		alen := int(binary.BigEndian.Uint16(buf[off+2 : off+4]))
+		off += 4
+		if off+alen > len(buf) {
+			break
+		}
+		val := buf[off : off+alen]
+		switch atype {
+		case attrXORMappedAddr:
+			if a, err := parseXORMappedAddress(val, txID); err == nil {
+				xor = a
+			}
+		case attrMappedAddress:
+			if a, err := parseMappedAddress(val); err == nil {
+				plain = a
+			}
+		}
+		off += alen + (4-alen%4)%4
+	}
+	if xor.IsValid() {
+		return xor, nil
+	}
+	if plain.IsValid() {
+		return plain, nil
+	}
+	return netip.AddrPort{}, errors.New("netcheck: no mapped-address attribute in response")
+}
+
+func parseMappedAddress(val []byte) (netip.AddrPort, error) {
+	if len(val) < 4 {
+		return netip.AddrPort{}, errShortAttr
+	}
+	port := binary.BigEndian.Uint16(val[2:4])
+	switch val[1] {
+	case familyIPv4:
+		if len(val) < 8 {
+			return netip.AddrPort{}, errShortAttr
+		}
+		return netip.AddrPortFrom(netip.AddrFrom4([4]byte(val[4:8])), port), nil
+	case familyIPv6:
+		if len(val) < 20 {
+			return netip.AddrPort{}, errShortAttr
+		}
+		return netip.AddrPortFrom(netip.AddrFrom16([16]byte(val[4:20])), port), nil
+	default:
+		return netip.AddrPort{}, fmt.Errorf("netcheck: unknown address family 0x%02x", val[1])
+	}
+}
+
+func parseXORMappedAddress(val, txID []byte) (netip.AddrPort, error) {
+	if len(val) < 4 {
+		return netip.AddrPort{}, errShortAttr
+	}
+	port := binary.BigEndian.Uint16(val[2:4]) ^ uint16(magicCookie>>16)
+	switch val[1] {
+	case familyIPv4:
+		if len(val) < 8 {
+			return netip.AddrPort{}, errShortAttr
+		}
+		var cookieBytes [4]byte
+		binary.BigEndian.PutUint32(cookieBytes[:], magicCookie)
+		var addrBytes [4]byte
+		for i := range addrBytes {
+			addrBytes[i] = val[4+i] ^ cookieBytes[i]
+		}
+		return netip.AddrPortFrom(netip.AddrFrom4(addrBytes), port), nil
+	case familyIPv6:
+		if len(val) < 20 {
+			return netip.AddrPort{}, errShortAttr
+		}
+		var xorKey [16]byte
+		binary.BigEndian.PutUint32(xorKey[0:4], magicCookie)
+		copy(xorKey[4:16], txID)
+		var addrBytes [16]byte
+		for i := range addrBytes {
+			addrBytes[i] = val[4+i] ^ xorKey[i]
+		}
+		return netip.AddrPortFrom(netip.AddrFrom16(addrBytes), port), nil
+	default:
+		return netip.AddrPort{}, fmt.Errorf("netcheck: unknown address family 0x%02x", val[1])
+	}
+}
+
+// localIPv4 returns the first non-loopback, non-link-local IPv4 address
+// configured on any local interface, or the zero Addr if none is found.
+func localIPv4() netip.Addr {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return netip.Addr{}
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		v4 := ipNet.IP.To4()
+		if v4 == nil {
+			continue
+		}
+		addr := netip.AddrFrom4([4]byte(v4))
+		if addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+			continue
+		}
+		return addr
+	}
+	return netip.Addr{}
+}
+
+// classifyLocalIPv6 scans local interface addresses for global, ULA
+// (fc00::/7), and link-local (fe80::/10) IPv6 addresses.
+func classifyLocalIPv6() (hasGlobal, hasULA, hasLinkLocal bool) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, false, false
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		switch {
+		case addr.IsLoopback():
+			continue
+		case addr.IsLinkLocalUnicast():
+			hasLinkLocal = true
+		case ulaPrefix.Contains(addr):
+			hasULA = true
+		default:
+			hasGlobal = true
+		}
+	}
+	return hasGlobal, hasULA, hasLinkLocal
+}
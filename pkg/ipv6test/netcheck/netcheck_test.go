@@ -0,0 +1,129 @@
+package netcheck
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func TestEncodeBindingRequest(t *testing.T) {
+	txID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	msg := encodeBindingRequest(txID, 0)
+
+	if len(msg) != 20 {
+		t.Fatalf("len(msg) = %d, want 20 (header only, no attributes)", len(msg))
+	}
+	if mtype := binary.BigEndian.Uint16(msg[0:2]); mtype != bindingRequest {
+		t.Fatalf("message type = 0x%04x, want 0x%04x", mtype, bindingRequest)
+	}
+	if cookie := binary.BigEndian.Uint32(msg[4:8]); cookie != magicCookie {
+		t.Fatalf("magic cookie = 0x%08x, want 0x%08x", cookie, magicCookie)
+	}
+	if string(msg[8:20]) != string(txID) {
+		t.Fatalf("transaction ID = %v, want %v", msg[8:20], txID)
+	}
+}
+
+func TestEncodeBindingRequestWithPadding(t *testing.T) {
+	txID := make([]byte, 12)
+	msg := encodeBindingRequest(txID, 300)
+
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	// PADDING value is padded up to a 4-byte boundary, plus the 4-byte
+	// attribute header.
+	wantLen := 300 + (4-300%4)%4 + 4
+	if length != wantLen {
+		t.Fatalf("header length field = %d, want %d", length, wantLen)
+	}
+	if len(msg) != 20+length {
+		t.Fatalf("len(msg) = %d, want %d", len(msg), 20+length)
+	}
+}
+
+// buildBindingResponse hand-assembles a STUN Binding Response carrying the
+// given attributes, so decodeBindingResponse can be tested without a real
+// STUN server.
+func buildBindingResponse(txID []byte, attrs ...[]byte) []byte {
+	var body []byte
+	for _, a := range attrs {
+		body = append(body, a...)
+	}
+	var hdr [20]byte
+	binary.BigEndian.PutUint16(hdr[0:2], bindingResponse)
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(body)))
+	binary.BigEndian.PutUint32(hdr[4:8], magicCookie)
+	copy(hdr[8:20], txID)
+	return append(hdr[:], body...)
+}
+
+func TestDecodeBindingResponseXORMappedIPv4(t *testing.T) {
+	txID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	want := netip.MustParseAddrPort("203.0.113.7:54321")
+
+	var val [8]byte
+	val[1] = familyIPv4
+	binary.BigEndian.PutUint16(val[2:4], want.Port()^uint16(magicCookie>>16))
+	var cookieBytes [4]byte
+	binary.BigEndian.PutUint32(cookieBytes[:], magicCookie)
+	addrBytes := want.Addr().As4()
+	for i := range addrBytes {
+		val[4+i] = addrBytes[i] ^ cookieBytes[i]
+	}
+	buf := buildBindingResponse(txID, encodeAttr(attrXORMappedAddr, val[:]))
+
+	got, err := decodeBindingResponse(buf, txID)
+	if err != nil {
+		t.Fatalf("decodeBindingResponse: %v", err)
+	}
+	if got != want {
+		t.Fatalf("decodeBindingResponse() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeBindingResponsePlainMappedFallback(t *testing.T) {
+	txID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	want := netip.MustParseAddrPort("203.0.113.7:54321")
+
+	var val [8]byte
+	val[1] = familyIPv4
+	binary.BigEndian.PutUint16(val[2:4], want.Port())
+	addrBytes := want.Addr().As4()
+	copy(val[4:8], addrBytes[:])
+	buf := buildBindingResponse(txID, encodeAttr(attrMappedAddress, val[:]))
+
+	got, err := decodeBindingResponse(buf, txID)
+	if err != nil {
+		t.Fatalf("decodeBindingResponse: %v", err)
+	}
+	if got != want {
+		t.Fatalf("decodeBindingResponse() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeBindingResponseTransactionIDMismatch(t *testing.T) {
+	txID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	other := []byte{9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9}
+	buf := buildBindingResponse(other)
+
+	if _, err := decodeBindingResponse(buf, txID); err == nil {
+		t.Fatal("expected an error for a mismatched transaction ID")
+	}
+}
+
+func TestDecodeBindingResponseBindingError(t *testing.T) {
+	txID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	var hdr [20]byte
+	binary.BigEndian.PutUint16(hdr[0:2], bindingError)
+	binary.BigEndian.PutUint32(hdr[4:8], magicCookie)
+	copy(hdr[8:20], txID)
+
+	if _, err := decodeBindingResponse(hdr[:], txID); err == nil {
+		t.Fatal("expected an error for a binding error response")
+	}
+}
+
+func TestDecodeBindingResponseShortMessage(t *testing.T) {
+	if _, err := decodeBindingResponse([]byte{0x01, 0x01}, nil); err == nil {
+		t.Fatal("expected an error for a message shorter than the STUN header")
+	}
+}
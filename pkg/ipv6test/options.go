@@ -2,9 +2,13 @@ package ipv6test
 
 import (
 	"fmt"
+	"net/netip"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
+	"github.com/falling-sky/source/pkg/ipv6test/ipmeta"
 )
 
 // Options controls how test URLs are built and executed.
@@ -15,6 +19,67 @@ type Options struct {
 	SlowThreshold time.Duration
 	PacketSize    int
 	HTTPUserAgent string
+
+	// Resolvers are the upstream DNS servers queried by the dnsprobe-backed
+	// tests (dns_a, dns_aaaa, ...). When set, they are used instead of the
+	// system resolver(s), which are then not queried; when empty, the
+	// system resolver(s) from /etc/resolv.conf are used.
+	Resolvers []netip.AddrPort
+
+	// EDNS0BufferSize is the UDP payload size advertised by dns_dnssec_ok
+	// queries. Defaults to 1232 (the DNS flag day recommendation) when zero.
+	// dns_edns0 does not use it: it always queries at 4096 and, when that
+	// truncates, retries at 1232.
+	EDNS0BufferSize uint16
+
+	// PMTUDSizes, when set, bounds the binary search pkg/ipv6test/pmtud
+	// runs for ipv6_mtu/dual_stack_mtu: its min/max become the search
+	// floor/ceiling. Empty defaults to floor 1280 (the IPv6 minimum) and
+	// ceiling PacketSize.
+	PMTUDSizes []int
+
+	// IPMeta enriches the observed IPv4/IPv6 addresses (ASN, org, tunnel
+	// detection, ...) via pkg/ipv6test/ipmeta. Enrichment is skipped when
+	// nil.
+	IPMeta ipmeta.Provider
+
+	// AddressPolicy controls which address family the HTTP-backed tests
+	// dial, via pkg/ipv6test/dialpolicy. Empty leaves the Go runtime's
+	// default dialer untouched, matching pre-existing behavior.
+	AddressPolicy dialpolicy.Policy
+
+	// PreferredFamily is "ipv4" or "ipv6"; it only affects
+	// dialpolicy.HappyEyeballs, where it picks which family gets the head
+	// start. Defaults to "ipv6" when empty.
+	PreferredFamily string
+
+	// HappyEyeballsHeadStart is how long the preferred family gets before
+	// the other family is raced alongside it. Defaults to
+	// dialpolicy.DefaultHeadStart when zero.
+	HappyEyeballsHeadStart time.Duration
+
+	// ExtraResolvers are additional resolvers probed directly by
+	// resolver_aaaa/resolver_doh6 (see pkg/ipv6test/dnsprobe.ParseResolver),
+	// as URIs: udp://host:port, tcp://host:port, tls://host:port (DoT), or
+	// https://host/path (DoH). Unlike Resolvers above, these aren't used
+	// for the dns_*/rdns_* tests.
+	ExtraResolvers []string
+
+	// STUNServersV4 and STUNServersV6 are the "host:port" STUN servers
+	// netcheck_v4/netcheck_v6 probe (see pkg/ipv6test/netcheck). Each is
+	// skipped (StatusSkipped) when its list is empty.
+	STUNServersV4 []string
+	STUNServersV6 []string
+
+	// MaxParallel bounds how many tests RunStream/Run run at once.
+	// Defaults to 4 when zero.
+	MaxParallel int
+
+	// MaxConnsPerHost bounds concurrent connections RunStream/Run's HTTP
+	// client opens to a single test host, so independent tests that hit
+	// the same host don't contend for more connections than the host
+	// wants to serve. Defaults to 6 when zero.
+	MaxConnsPerHost int
 }
 
 const (
@@ -24,6 +89,7 @@ const (
 	defaultSlowThreshold = 5 * time.Second
 	defaultPacketSize    = 1600
 	defaultUserAgent     = "testipv6-go/0.1"
+	defaultEDNS0Buffer   = 1232
 )
 
 // Domains we control and want to keep under a single wildcard (*.toany.net).
@@ -34,12 +100,13 @@ var wildcardDomains = map[string]struct{}{
 // DefaultOptions builds a ready-to-use Options.
 func DefaultOptions() Options {
 	return Options{
-		Domain:        defaultDomain,
-		Endpoints:     DefaultEndpoints(defaultDomain, defaultLookupDomain, defaultPacketSize),
-		Timeout:       defaultTimeout,
-		SlowThreshold: defaultSlowThreshold,
-		PacketSize:    defaultPacketSize,
-		HTTPUserAgent: defaultUserAgent,
+		Domain:          defaultDomain,
+		Endpoints:       DefaultEndpoints(defaultDomain, defaultLookupDomain, defaultPacketSize),
+		Timeout:         defaultTimeout,
+		SlowThreshold:   defaultSlowThreshold,
+		PacketSize:      defaultPacketSize,
+		HTTPUserAgent:   defaultUserAgent,
+		EDNS0BufferSize: defaultEDNS0Buffer,
 	}
 }
 
@@ -76,6 +143,30 @@ func DefaultEndpoints(domain string, lookupDomain string, packetSize int) map[Te
 	}
 }
 
+// MTUProbeURL builds the ipv6_mtu endpoint URL for a single PMTUD probe
+// size, reusing the same size/fill query shape as DefaultEndpoints' mkMTU
+// but parameterized per call instead of fixed at opts.PacketSize.
+func MTUProbeURL(domain string, size int) string {
+	trimmed := strings.TrimSpace(domain)
+	if trimmed == "" {
+		trimmed = defaultDomain
+	}
+	fill := strings.Repeat("x", size)
+	return fmt.Sprintf("https://mtu1280.%s/ip/?callback=?&size=%d&fill=%s", trimmed, size, url.QueryEscape(fill))
+}
+
+// DualStackMTUProbeURL builds the dual_stack_mtu endpoint URL for a single
+// PMTUD probe size, mirroring MTUProbeURL but against the dual-stack ("ds")
+// host instead of the IPv6-only ("mtu1280") host.
+func DualStackMTUProbeURL(domain string, size int) string {
+	trimmed := strings.TrimSpace(domain)
+	if trimmed == "" {
+		trimmed = defaultDomain
+	}
+	fill := strings.Repeat("x", size)
+	return fmt.Sprintf("https://ds.%s/ip/?callback=?&size=%d&fill=%s", trimmed, size, url.QueryEscape(fill))
+}
+
 func v6nsHost(useWildcard bool) string {
 	if useWildcard {
 		// Single-level host fits under *.domain wildcard.
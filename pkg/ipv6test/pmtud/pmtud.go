@@ -0,0 +1,338 @@
+// Package pmtud discovers the real path MTU to an IPv6 destination instead
+// of inferring it from a single padded HTTP request. TestIPv6MTU/
+// TestDualStackMTU used to POST/GET one fixed-size query string and call the
+// run "bad" on any failure, which cannot tell an MTU black-hole (large
+// packets vanish, nothing comes back) apart from an ordinary TLS or
+// connection error.
+//
+// ProbeBisect instead binary searches for the effective payload size. When
+// the process holds CAP_NET_RAW (or runs as root), it sends raw ICMPv6 echo
+// requests with IPV6_MTU_DISCOVER=IPV6_PMTUDISC_DO set (the IPv6 analogue
+// of the IPv4 DF bit) and reads back either an echo reply, an ICMPv6
+// Packet Too Big carrying the offending link's MTU, or nothing at all.
+// Otherwise it falls back to HTTPS requests against a size-controlled
+// endpoint, constraining the dial socket's TCP_MAXSEG so that a size which
+// should fit but times out points at fragmentation rather than a TLS
+// failure. (IPV6_USE_MIN_MTU has no Linux equivalent exposed by
+// golang.org/x/sys/unix, so the HTTP fallback relies on TCP_MAXSEG alone.)
+package pmtud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// protocolIPv6ICMP is IPPROTO_ICMPV6 (RFC 4443), duplicated here because
+// golang.org/x/net/icmp only exposes it via an internal package.
+const protocolIPv6ICMP = 58
+
+// Outcome classifies a PMTU probe beyond a plain pass/fail.
+type Outcome string
+
+const (
+	OutcomeOK           Outcome = "ok"            // the largest tested size got through
+	OutcomeBlackhole    Outcome = "blackhole"     // large sizes silently drop, but 1280 works
+	OutcomeBad          Outcome = "bad"           // a reply (RST/TLS/ICMP error) says this isn't a size problem
+	OutcomeTimeout      Outcome = "timeout"       // nothing worked, including 1280
+	OutcomeICMPFiltered Outcome = "icmp_filtered" // ICMPv6 itself got no reply of any kind, but HTTP at the same sizes works: something on path is dropping ICMPv6, not the payload sizes
+)
+
+// Method names reported in Result.Method.
+const (
+	MethodICMP = "icmp"
+	MethodHTTP = "http"
+)
+
+// SizeProbe records the outcome for one payload size.
+type SizeProbe struct {
+	Size    int
+	OK      bool
+	Timeout bool
+	Err     string
+}
+
+// Result is the outcome of a path-MTU discovery run.
+type Result struct {
+	Method        string
+	DiscoveredMTU int
+	Outcome       Outcome
+	Sizes         []SizeProbe
+
+	// Inferred is true when Method is MethodHTTP because a raw ICMPv6
+	// socket wasn't available (no CAP_NET_RAW/not root): the MTU is
+	// inferred from TCP_MAXSEG-constrained HTTP requests rather than
+	// measured directly from ICMPv6 Packet Too Big messages.
+	Inferred bool
+}
+
+// URLForSize builds the HTTP fallback probe URL for a given payload size.
+type URLForSize func(size int) string
+
+// openICMPv6Socket opens a raw ICMPv6 socket with IPV6_PMTUDISC_DO set (so
+// oversized packets get fragmented-never and instead elicit a Packet Too
+// Big back), timed out at ctx's deadline or 5s. The bool return is false
+// when the socket can't be opened, typically because the process isn't
+// privileged (no CAP_NET_RAW/root) or addr isn't an IPv6 address.
+func openICMPv6Socket(ctx context.Context, addr netip.Addr) (fd int, dst *unix.SockaddrInet6, id int, ok bool) {
+	if !addr.Is6() {
+		return 0, nil, 0, false
+	}
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_RAW, unix.IPPROTO_ICMPV6)
+	if err != nil {
+		return 0, nil, 0, false
+	}
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_DO); err != nil {
+		unix.Close(fd)
+		return 0, nil, 0, false
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		tv := unix.NsecToTimeval(time.Until(dl).Nanoseconds())
+		_ = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+	} else {
+		tv := unix.NsecToTimeval((5 * time.Second).Nanoseconds())
+		_ = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+	}
+	d := unix.SockaddrInet6{Addr: addr.As16()}
+	return fd, &d, os.Getpid() & 0xffff, true
+}
+
+// ProbeBisect discovers the effective path MTU to addr by binary search
+// between floor and ceiling (inclusive) instead of testing a fixed list of
+// sizes: each step probes the midpoint of the still-undetermined half of
+// the range, needing O(log n) probes instead of one per candidate size.
+// It prefers the raw ICMPv6 method and falls back to the HTTP method,
+// setting Result.Inferred when it does. When the ICMPv6 probe gets no
+// reply of any kind at any size (not even a Packet Too Big) but the HTTP
+// probe succeeds at the same sizes, that's reported as
+// OutcomeICMPFiltered: ICMPv6 itself looks blocked on this path, not the
+// path being down.
+func ProbeBisect(ctx context.Context, addr netip.Addr, floor, ceiling int, urlForSize URLForSize, client *http.Client) Result {
+	if floor <= 0 {
+		floor = 1280
+	}
+	if ceiling < floor {
+		ceiling = floor
+	}
+
+	if fd, dst, id, ok := openICMPv6Socket(ctx, addr); ok {
+		defer unix.Close(fd)
+		discovered, sizes := bisectMTU(floor, ceiling, func(size, seq int) SizeProbe {
+			return probeICMPSize(fd, dst, id, seq, size)
+		})
+		res := Result{Method: MethodICMP, DiscoveredMTU: discovered, Sizes: sizes}
+		if discovered == 0 && allTimeout(sizes) {
+			if httpDiscovered, httpSizes := bisectMTU(floor, ceiling, func(size, _ int) SizeProbe {
+				return probeHTTPSize(ctx, urlForSize(size), size, client)
+			}); httpDiscovered > 0 {
+				res.Outcome = OutcomeICMPFiltered
+				res.DiscoveredMTU = httpDiscovered
+				res.Sizes = httpSizes
+				return res
+			}
+			res.Outcome = OutcomeTimeout
+			return res
+		}
+		res.Outcome = classifyBisect(discovered, floor, ceiling)
+		if res.Outcome == OutcomeBlackhole && !allTimeout(sizes[:len(sizes)-1]) {
+			// A larger size failed with a Packet Too Big (or another
+			// error) instead of vanishing, so nothing was silently dropped.
+			res.Outcome = OutcomeOK
+		}
+		return res
+	}
+
+	discovered, sizes := bisectMTU(floor, ceiling, func(size, _ int) SizeProbe {
+		return probeHTTPSize(ctx, urlForSize(size), size, client)
+	})
+	return Result{
+		Method:        MethodHTTP,
+		DiscoveredMTU: discovered,
+		Sizes:         sizes,
+		Inferred:      true,
+		Outcome:       classifyBisect(discovered, floor, ceiling),
+	}
+}
+
+// bisectMTU binary searches [floor, ceiling] for the largest size probeAt
+// reports OK, anchoring on floor first since a binary search over a range
+// whose floor already fails isn't meaningful. Returns 0 if even floor
+// fails. sizes is returned largest-first to match classify-style readers.
+func bisectMTU(floor, ceiling int, probeAt func(size, seq int) SizeProbe) (discovered int, sizes []SizeProbe) {
+	seq := 1
+	floorProbe := probeAt(floor, seq)
+	sizes = append(sizes, floorProbe)
+	if !floorProbe.OK {
+		return 0, sizes
+	}
+	discovered = floor
+
+	lo, hi := floor+1, ceiling
+	for lo <= hi {
+		seq++
+		mid := (lo + hi) / 2
+		sp := probeAt(mid, seq)
+		sizes = append(sizes, sp)
+		if sp.OK {
+			discovered = mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Size > sizes[j].Size })
+	return discovered, sizes
+}
+
+// classifyBisect turns a bisect discovery into an Outcome: the full
+// ceiling getting through is ok, nothing at all (not even floor) working
+// is timeout, only floor working is a blackhole (big sizes vanish, the
+// IPv6 minimum doesn't), and anything in between is still ok, just at a
+// smaller-than-ceiling effective MTU.
+func classifyBisect(discovered, floor, ceiling int) Outcome {
+	switch {
+	case discovered <= 0:
+		return OutcomeTimeout
+	case discovered == floor && floor < ceiling:
+		return OutcomeBlackhole
+	default:
+		return OutcomeOK
+	}
+}
+
+// probeICMPSize sends one echo request of size bytes of payload and waits
+// for a reply: an echo reply means the size got through, a Packet Too Big
+// means an on-path link reported its MTU, and a timeout means the packet
+// (or its reply) silently vanished.
+func probeICMPSize(fd int, dst *unix.SockaddrInet6, id, seq, size int) SizeProbe {
+	sp := SizeProbe{Size: size}
+	payload := make([]byte, echoPayloadLen(size))
+	msg := icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+	}
+	wire, err := msg.Marshal(nil) // psh=nil: kernel computes the ICMPv6 checksum on a raw socket
+	if err != nil {
+		sp.Err = err.Error()
+		return sp
+	}
+	if err := unix.Sendto(fd, wire, 0, dst); err != nil {
+		sp.Err = err.Error()
+		return sp
+	}
+
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EWOULDBLOCK {
+				sp.Timeout = true
+			} else {
+				sp.Err = err.Error()
+			}
+			return sp
+		}
+		reply, err := icmp.ParseMessage(protocolIPv6ICMP, buf[:n])
+		if err != nil {
+			continue
+		}
+		switch body := reply.Body.(type) {
+		case *icmp.Echo:
+			if reply.Type == ipv6.ICMPTypeEchoReply && body.ID == id && body.Seq == seq {
+				sp.OK = true
+				return sp
+			}
+		case *icmp.PacketTooBig:
+			if echoIDMatches(body.Data, id, seq) {
+				sp.Err = fmt.Sprintf("packet too big, mtu=%d", body.MTU)
+				return sp
+			}
+		}
+	}
+}
+
+// echoPayloadLen returns the Data length that makes the wire ICMPv6 echo
+// request (8-byte ICMPv6 header + 40-byte IPv6 header) add up to size.
+func echoPayloadLen(size int) int {
+	const headers = 40 + 8
+	if size <= headers {
+		return 0
+	}
+	return size - headers
+}
+
+// echoIDMatches reports whether the original datagram embedded in a
+// Packet Too Big message is the echo request we sent (ID+Seq in the
+// ICMPv6 header, 8 bytes into the echoed IPv6 packet).
+func echoIDMatches(original []byte, id, seq int) bool {
+	const ipv6HeaderLen = 40
+	if len(original) < ipv6HeaderLen+8 {
+		return false
+	}
+	icmpHdr := original[ipv6HeaderLen:]
+	gotID := int(icmpHdr[4])<<8 | int(icmpHdr[5])
+	gotSeq := int(icmpHdr[6])<<8 | int(icmpHdr[7])
+	return gotID == id && gotSeq == seq
+}
+
+func probeHTTPSize(ctx context.Context, url string, size int, base *http.Client) SizeProbe {
+	sp := SizeProbe{Size: size}
+	client := *base
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: base.Timeout,
+			Control: func(_, _ string, c syscall.RawConn) error {
+				var sockErr error
+				if err := c.Control(func(fd uintptr) {
+					sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG, size)
+				}); err != nil {
+					return err
+				}
+				return sockErr
+			},
+		}).DialContext,
+	}
+	client.Transport = transport
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		sp.Err = err.Error()
+		return sp
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			sp.Timeout = true
+		} else {
+			sp.Err = err.Error()
+		}
+		return sp
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		sp.OK = true
+	} else {
+		sp.Err = fmt.Sprintf("http status %d", resp.StatusCode)
+	}
+	return sp
+}
+
+func allTimeout(sizes []SizeProbe) bool {
+	for _, sp := range sizes {
+		if !sp.Timeout {
+			return false
+		}
+	}
+	return true
+}
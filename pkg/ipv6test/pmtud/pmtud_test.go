@@ -0,0 +1,102 @@
+package pmtud
+
+import "testing"
+
+func TestBisectMTUFloorFails(t *testing.T) {
+	discovered, sizes := bisectMTU(1280, 1500, func(size, seq int) SizeProbe {
+		return SizeProbe{Size: size, Timeout: true}
+	})
+	if discovered != 0 {
+		t.Fatalf("discovered = %d, want 0 when even the floor fails", discovered)
+	}
+	if len(sizes) != 1 || sizes[0].Size != 1280 {
+		t.Fatalf("sizes = %+v, want a single floor probe", sizes)
+	}
+}
+
+func TestBisectMTUConverges(t *testing.T) {
+	const realMTU = 1420
+	probes := 0
+	discovered, sizes := bisectMTU(1280, 1500, func(size, seq int) SizeProbe {
+		probes++
+		return SizeProbe{Size: size, OK: size <= realMTU}
+	})
+	if discovered != realMTU {
+		t.Fatalf("discovered = %d, want %d", discovered, realMTU)
+	}
+	// log2(1500-1280) =~ 8 steps plus the floor probe; well under a linear
+	// scan of every size in range.
+	if probes > 10 {
+		t.Fatalf("bisectMTU took %d probes to converge, want <= 10", probes)
+	}
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i-1].Size < sizes[i].Size {
+			t.Fatalf("sizes not sorted largest-first: %+v", sizes)
+		}
+	}
+}
+
+func TestBisectMTUCeilingAllOK(t *testing.T) {
+	discovered, _ := bisectMTU(1280, 1500, func(size, seq int) SizeProbe {
+		return SizeProbe{Size: size, OK: true}
+	})
+	if discovered != 1500 {
+		t.Fatalf("discovered = %d, want 1500 (ceiling) when every size succeeds", discovered)
+	}
+}
+
+func TestClassifyBisect(t *testing.T) {
+	cases := []struct {
+		name           string
+		discovered     int
+		floor, ceiling int
+		want           Outcome
+	}{
+		{"timeout", 0, 1280, 1500, OutcomeTimeout},
+		{"blackhole_floor_only", 1280, 1280, 1500, OutcomeBlackhole},
+		{"ok_full_ceiling", 1500, 1280, 1500, OutcomeOK},
+		{"ok_partial", 1420, 1280, 1500, OutcomeOK},
+		{"floor_equals_ceiling_is_ok_not_blackhole", 1280, 1280, 1280, OutcomeOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := classifyBisect(c.discovered, c.floor, c.ceiling)
+			if got != c.want {
+				t.Fatalf("classifyBisect(%d, %d, %d) = %s, want %s", c.discovered, c.floor, c.ceiling, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEchoPayloadLen(t *testing.T) {
+	cases := []struct {
+		size int
+		want int
+	}{
+		{1500, 1452},
+		{1280, 1232},
+		{48, 0}, // at or below the 48-byte header floor
+		{10, 0},
+	}
+	for _, c := range cases {
+		if got := echoPayloadLen(c.size); got != c.want {
+			t.Fatalf("echoPayloadLen(%d) = %d, want %d", c.size, got, c.want)
+		}
+	}
+}
+
+func TestEchoIDMatches(t *testing.T) {
+	original := make([]byte, 40+8)
+	original[40+4], original[40+5] = 0x00, 0x07 // ID = 7
+	original[40+6], original[40+7] = 0x00, 0x2a // Seq = 42
+
+	if !echoIDMatches(original, 7, 42) {
+		t.Fatal("echoIDMatches should match the embedded ID/Seq")
+	}
+	if echoIDMatches(original, 7, 43) {
+		t.Fatal("echoIDMatches should not match a different Seq")
+	}
+	if echoIDMatches(original[:40], 7, 42) {
+		t.Fatal("echoIDMatches should reject a datagram too short to contain an ICMPv6 header")
+	}
+}
@@ -8,7 +8,16 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/netip"
+	"strings"
 	"time"
+
+	"github.com/falling-sky/source/pkg/ipv6test/addrselect"
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
+	"github.com/falling-sky/source/pkg/ipv6test/dnsprobe"
+	"github.com/falling-sky/source/pkg/ipv6test/ipmeta"
+	"github.com/falling-sky/source/pkg/ipv6test/netcheck"
+	"github.com/falling-sky/source/pkg/ipv6test/pmtud"
 )
 
 // Runner executes connectivity tests.
@@ -28,22 +37,49 @@ func NewRunner(opts Options) *Runner {
 	return &Runner{client: c, options: opts}
 }
 
+// ReloadGeo reopens the backing mmdb files of Options.IPMeta, when that
+// provider supports it (see ipmeta.Reloadable), so a long-running
+// testipv6-server can pick up a refreshed GeoLite2 database without a
+// restart. It's a no-op when IPMeta is nil or doesn't implement Reload.
+func (r *Runner) ReloadGeo() error {
+	reloadable, ok := r.options.IPMeta.(ipmeta.Reloadable)
+	if !ok {
+		return nil
+	}
+	return reloadable.Reload()
+}
+
 // Catalog lists supported tests with example URLs.
 func Catalog(opts Options) []Definition {
 	return []Definition{
 		{Name: TestIPv4DNS, Description: "A-only hostname reachability", Category: "connectivity", RequiresIPv6: false, LargePayload: false, ExampleURL: opts.Endpoints[TestIPv4DNS]},
 		{Name: TestIPv6DNS, Description: "AAAA-only hostname reachability", Category: "connectivity", RequiresIPv6: true, LargePayload: false, ExampleURL: opts.Endpoints[TestIPv6DNS]},
 		{Name: TestDualStack, Description: "Dual-stack hostname reachability", Category: "connectivity", RequiresIPv6: false, LargePayload: false, ExampleURL: opts.Endpoints[TestDualStack]},
-		{Name: TestDualStackMTU, Description: "Dual-stack large-payload reachability", Category: "mtu", RequiresIPv6: false, LargePayload: true, ExampleURL: opts.Endpoints[TestDualStackMTU], PacketSize: opts.PacketSize},
+		{Name: TestDualStackMTU, Description: "Dual-stack large-payload reachability", Category: "mtu", RequiresIPv6: false, LargePayload: true, ExampleURL: opts.Endpoints[TestDualStackMTU], PacketSize: opts.PacketSize, DependsOn: dependsOn(TestDualStackMTU)},
 		{Name: TestIPv6MTU, Description: "IPv6 large-payload reachability", Category: "mtu", RequiresIPv6: true, LargePayload: true, ExampleURL: opts.Endpoints[TestIPv6MTU], PacketSize: opts.PacketSize},
 		{Name: TestDNSV6Resolver, Description: "Resolver reachability to IPv6-only auth", Category: "dns", RequiresIPv6: false, LargePayload: false, ExampleURL: opts.Endpoints[TestDNSV6Resolver]},
-		{Name: TestASNLookupV4, Description: "ASN lookup over IPv4", Category: "metadata", RequiresIPv6: false, LargePayload: false, ExampleURL: opts.Endpoints[TestASNLookupV4]},
-		{Name: TestASNLookupV6, Description: "ASN lookup over IPv6", Category: "metadata", RequiresIPv6: true, LargePayload: false, ExampleURL: opts.Endpoints[TestASNLookupV6]},
+		{Name: TestASNLookupV4, Description: "ASN lookup over IPv4", Category: "metadata", RequiresIPv6: false, LargePayload: false, ExampleURL: opts.Endpoints[TestASNLookupV4], DependsOn: dependsOn(TestASNLookupV4)},
+		{Name: TestASNLookupV6, Description: "ASN lookup over IPv6", Category: "metadata", RequiresIPv6: true, LargePayload: false, ExampleURL: opts.Endpoints[TestASNLookupV6], DependsOn: dependsOn(TestASNLookupV6)},
+		{Name: TestDNSA, Description: "Native A query against the system/explicit resolver(s)", Category: "dns", RequiresIPv6: false, LargePayload: false},
+		{Name: TestDNSAAAA, Description: "Native AAAA query against the system/explicit resolver(s)", Category: "dns", RequiresIPv6: true, LargePayload: false},
+		{Name: TestDNSEDNS0, Description: "AAAA query with an EDNS0 OPT RR, to catch resolver-path PMTU/fragmentation issues", Category: "dns", RequiresIPv6: true, LargePayload: false},
+		{Name: TestDNSDNSSECOk, Description: "Query with the DNSSEC-OK bit set, to catch resolvers that strip RRSIG/DNSKEY", Category: "dns", RequiresIPv6: false, LargePayload: false},
+		{Name: TestDNSGlueV6, Description: "AAAA query against a delegation that has no IPv6 glue", Category: "dns", RequiresIPv6: true, LargePayload: false},
+		{Name: TestRDNSv4, Description: "PTR lookup and forward-confirmation of the observed IPv4 address", Category: "dns", RequiresIPv6: false, LargePayload: false, DependsOn: dependsOn(TestRDNSv4)},
+		{Name: TestRDNSv6, Description: "PTR lookup and forward-confirmation of the observed IPv6 address", Category: "dns", RequiresIPv6: true, LargePayload: false, DependsOn: dependsOn(TestRDNSv6)},
+		{Name: TestResolverAAAA, Description: "Every configured extra resolver (UDP/TCP/DoT/DoH) returns AAAA for a dual-stack name", Category: "dns", RequiresIPv6: true, LargePayload: false},
+		{Name: TestResolverDoH6, Description: "At least one configured DoH resolver is itself reachable over IPv6", Category: "dns", RequiresIPv6: true, LargePayload: false},
+		{Name: TestNetcheckV4, Description: "STUN-based external mapping, NAT type, hairpin, and CGNAT classification over IPv4", Category: "netcheck", RequiresIPv6: false, LargePayload: false},
+		{Name: TestNetcheckV6, Description: "STUN-based external mapping, NAT type, hairpin, and link-local/ULA-only classification over IPv6", Category: "netcheck", RequiresIPv6: true, LargePayload: false},
 	}
 }
 
-// Run executes a batch synchronously.
-func (r *Runner) Run(ctx context.Context, req RunRequest) (RunResult, error) {
+// defaultTests is the test set Run/RunStream use when RunRequest.Tests is
+// empty.
+var defaultTests = []TestName{TestIPv4DNS, TestIPv6DNS, TestDualStack, TestDualStackMTU, TestIPv6MTU, TestDNSV6Resolver, TestASNLookupV4, TestASNLookupV6}
+
+// mergeOptions overlays req's per-run overrides onto r.options.
+func (r *Runner) mergeOptions(req RunRequest) Options {
 	opts := r.options
 	if req.Timeout > 0 {
 		opts.Timeout = req.Timeout
@@ -54,43 +90,180 @@ func (r *Runner) Run(ctx context.Context, req RunRequest) (RunResult, error) {
 	if req.PacketSizeBytes > 0 {
 		opts.PacketSize = req.PacketSizeBytes
 	}
+	if req.AddressPolicy != "" {
+		opts.AddressPolicy = req.AddressPolicy
+	}
+	return opts
+}
 
-	// Clone client with per-run timeout.
+// clientFor clones r.client for a single run: a per-run timeout, and a
+// dedicated Transport (so neither the custom dialer nor the per-host
+// connection cap leaks onto other runs sharing r.client).
+func (r *Runner) clientFor(opts Options) *http.Client {
 	client := *r.client
 	client.Timeout = opts.Timeout
 
-	tests := req.Tests
-	if len(tests) == 0 {
-		tests = []TestName{TestIPv4DNS, TestIPv6DNS, TestDualStack, TestDualStackMTU, TestIPv6MTU, TestDNSV6Resolver, TestASNLookupV4, TestASNLookupV6}
+	maxConnsPerHost := opts.MaxConnsPerHost
+	if maxConnsPerHost <= 0 {
+		maxConnsPerHost = defaultMaxConnsPerHost
+	}
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		MaxConnsPerHost: maxConnsPerHost,
+	}
+	if opts.AddressPolicy != "" {
+		headStart := opts.HappyEyeballsHeadStart
+		if headStart <= 0 {
+			headStart = dialpolicy.DefaultHeadStart
+		}
+		transport.DialContext = dialpolicy.DialContextFunc(opts.AddressPolicy, opts.PreferredFamily, headStart)
 	}
+	client.Transport = transport
+	return &client
+}
 
-	start := time.Now()
-	result := RunResult{
-		RunID:           randomRunID(),
-		StartedAt:       start,
-		SlowThresholdMs: opts.SlowThreshold.Milliseconds(),
-		TimeoutMs:       opts.Timeout.Milliseconds(),
-		PacketSizeBytes: opts.PacketSize,
-	}
-
-	for _, tn := range tests {
-		tr := r.runSingle(ctx, &client, opts, tn)
-		result.Results = append(result.Results, tr)
-		if tr.IP != nil {
-			switch tr.IP.Type {
-			case "ipv4":
-				if result.IPv4 == nil {
-					result.IPv4 = tr.IP
-				}
-			case "ipv6":
-				if result.IPv6 == nil {
-					result.IPv6 = tr.IP
-				}
+// runDispatch executes a single test given whatever prerequisite IP
+// observations are already available (ipv4/ipv6 may be nil). It's the
+// shared dispatch switch behind both Run's sequential loop and
+// RunStream's concurrent scheduler.
+func (r *Runner) runDispatch(ctx context.Context, client *http.Client, opts Options, tn TestName, ipv4, ipv6 *IpObservation) TestResult {
+	switch {
+	case tn == TestRDNSv4 || tn == TestRDNSv6:
+		return r.runPTRProbe(ctx, opts, tn, ipv4, ipv6)
+	case IsDNSTest(tn):
+		return r.runDNSProbe(ctx, opts, tn)
+	case tn == TestIPv6MTU || tn == TestDualStackMTU:
+		return r.runPMTUDProbe(ctx, client, opts, tn)
+	case tn == TestResolverAAAA || tn == TestResolverDoH6:
+		return r.runResolverProbe(ctx, opts, tn)
+	case tn == TestNetcheckV4 || tn == TestNetcheckV6:
+		return r.runNetcheckProbe(ctx, opts, tn)
+	default:
+		return r.runSingle(ctx, client, opts, tn)
+	}
+}
+
+// Run executes a batch synchronously. It's a thin wrapper over RunStream
+// that discards progress Events and waits for the single aggregate
+// RunResult, so existing callers see no behavior change from the
+// concurrent scheduler underneath.
+func (r *Runner) Run(ctx context.Context, req RunRequest) (RunResult, error) {
+	events, results := r.RunStream(ctx, req)
+	for range events {
+	}
+	return <-results, nil
+}
+
+// enrichIPMeta looks up obs.IP via provider and copies the findings onto
+// obs. It's a no-op when obs is nil (the family wasn't observed this
+// run) or its address doesn't parse, and it swallows lookup errors since
+// enrichment is best-effort commentary on top of the connectivity result.
+func enrichIPMeta(ctx context.Context, provider ipmeta.Provider, obs *IpObservation) {
+	if obs == nil || obs.IP == "" {
+		return
+	}
+	addr, err := netip.ParseAddr(obs.IP)
+	if err != nil {
+		return
+	}
+	meta, err := provider.Lookup(ctx, addr)
+	if err != nil {
+		return
+	}
+	if meta.ASN != 0 {
+		obs.ASN = meta.ASN
+	}
+	if name := firstNonEmpty(meta.ASNName, meta.Org); name != "" {
+		obs.ASNName = name
+	}
+	obs.Country = meta.Country
+	obs.RegisteredCountry = meta.RegisteredCountry
+	obs.IsAnycast = meta.IsAnycast
+	obs.IsULA = meta.IsULA
+	obs.IsLinkLocal = meta.IsLinkLocal
+	if meta.IsTunnel {
+		obs.Subtype = meta.TunnelKind
+	}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// computeAddrSelection predicts, via RFC 6724 destination address selection
+// (pkg/ipv6test/addrselect), which family the client's OS would pick for
+// the dual-stack test endpoint, and compares that against which family the
+// dual_stack HTTP probe actually used. Returns nil when either of the
+// client's source addresses is unavailable or no resolver is configured or
+// discovered; a missing A or AAAA record still yields a prediction (see
+// addrselect.ChooseDualStack).
+func (r *Runner) computeAddrSelection(ctx context.Context, opts Options, result RunResult) *AddrSelectionResult {
+	if result.IPv4 == nil || result.IPv6 == nil {
+		return nil
+	}
+	srcV4, err := netip.ParseAddr(result.IPv4.IP)
+	if err != nil {
+		return nil
+	}
+	srcV6, err := netip.ParseAddr(result.IPv6.IP)
+	if err != nil {
+		return nil
+	}
+
+	resolvers := opts.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = dnsprobe.SystemResolvers()
+	}
+	if len(resolvers) == 0 {
+		return nil
+	}
+
+	host := "ds." + strings.TrimSpace(opts.Domain)
+	dstV4 := resolveFirst(ctx, resolvers, host, dnsprobe.TypeA)
+	dstV6 := resolveFirst(ctx, resolvers, host, dnsprobe.TypeAAAA)
+
+	choice := addrselect.ChooseDualStack(srcV4, srcV6, dstV4, dstV6)
+	actual := actualDualStackFamily(result)
+	return &AddrSelectionResult{
+		PredictedFamily: choice.PreferredFamily,
+		Rule:            choice.Rule,
+		LabelMismatch:   choice.LabelMismatch,
+		ActualFamily:    actual,
+		Matches:         actual == "" || actual == choice.PreferredFamily,
+	}
+}
+
+// resolveFirst returns the first answer of qtype for name from any of the
+// given resolvers, or the zero netip.Addr if none respond usefully.
+func resolveFirst(ctx context.Context, resolvers []netip.AddrPort, name string, qtype dnsprobe.Type) netip.Addr {
+	for _, resolver := range resolvers {
+		res, err := dnsprobe.Do(ctx, resolver, dnsprobe.Query{Name: name, Qtype: qtype})
+		if err != nil || res.RCODE != 0 {
+			continue
+		}
+		for _, a := range res.Answers {
+			if addr, err := netip.ParseAddr(a); err == nil {
+				return addr
 			}
 		}
 	}
-	result.DurationMs = time.Since(start).Milliseconds()
-	return result, nil
+	return netip.Addr{}
+}
+
+// actualDualStackFamily reports which family the dual_stack HTTP probe
+// actually connected over, or "" if that test didn't run or yield an IP.
+func actualDualStackFamily(result RunResult) string {
+	for _, tr := range result.Results {
+		if tr.Name == TestDualStack && tr.IP != nil {
+			return tr.IP.Type
+		}
+	}
+	return ""
 }
 
 func (r *Runner) runSingle(ctx context.Context, client *http.Client, opts Options, tn TestName) TestResult {
@@ -98,7 +271,8 @@ func (r *Runner) runSingle(ctx context.Context, client *http.Client, opts Option
 	if !ok || url == "" {
 		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no endpoint configured"}
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	dialCtx, outcome := dialpolicy.NewContext(ctx)
+	req, err := http.NewRequestWithContext(dialCtx, http.MethodGet, url, nil)
 	if err != nil {
 		return TestResult{Name: tn, Status: StatusError, Error: err.Error(), URL: url}
 	}
@@ -109,11 +283,15 @@ func (r *Runner) runSingle(ctx context.Context, client *http.Client, opts Option
 	duration := time.Since(start)
 
 	tr := TestResult{
-		Name:       tn,
-		URL:        url,
-		PacketSize: opts.PacketSize,
-		Duration:   duration,
-		TimeMs:     duration.Milliseconds(),
+		Name:            tn,
+		URL:             url,
+		PacketSize:      opts.PacketSize,
+		Duration:        duration,
+		TimeMs:          duration.Milliseconds(),
+		FamilyUsed:      outcome.FamilyUsed,
+		CandidatesTried: outcome.CandidatesTried,
+		Fallback:        outcome.Fallback,
+		FilteredFamily:  outcome.FilteredFamily,
 	}
 
 	if err != nil {
@@ -148,6 +326,488 @@ func (r *Runner) runSingle(ctx context.Context, client *http.Client, opts Option
 	return tr
 }
 
+// runPMTUDProbe serves ipv6_mtu and dual_stack_mtu via pkg/ipv6test/pmtud
+// instead of a single padded GET: it resolves the probe host's AAAA
+// record, then binary searches payload sizes between a floor (1280, the
+// IPv6 minimum) and a ceiling (opts.PacketSize, or opts.PMTUDSizes'
+// min/max when set) to report a concrete effective PMTU instead of a
+// plain slow/timeout verdict, telling an MTU black-hole and ICMPv6
+// filtering apart from an ordinary connection failure.
+func (r *Runner) runPMTUDProbe(ctx context.Context, client *http.Client, opts Options, tn TestName) TestResult {
+	url := opts.Endpoints[tn]
+	tr := TestResult{Name: tn, URL: url, PacketSize: opts.PacketSize}
+
+	resolvers := opts.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = dnsprobe.SystemResolvers()
+	}
+	if len(resolvers) == 0 {
+		tr.Status = StatusSkipped
+		tr.Notes = "no resolvers configured and none discovered"
+		return tr
+	}
+
+	d := strings.TrimSpace(opts.Domain)
+	if d == "" {
+		d = defaultDomain
+	}
+	hostPrefix, urlForSize := "mtu1280", func(size int) string { return MTUProbeURL(d, size) }
+	if tn == TestDualStackMTU {
+		hostPrefix, urlForSize = "ds", func(size int) string { return DualStackMTUProbeURL(d, size) }
+	}
+	addr := resolveFirst(ctx, resolvers, hostPrefix+"."+d, dnsprobe.TypeAAAA)
+	if !addr.IsValid() {
+		tr.Status = StatusSkipped
+		tr.Notes = fmt.Sprintf("could not resolve %s host's AAAA record", hostPrefix)
+		return tr
+	}
+
+	floor, ceiling := 1280, opts.PacketSize
+	if ceiling < floor {
+		ceiling = defaultPacketSize
+	}
+	if len(opts.PMTUDSizes) > 0 {
+		floor, ceiling = minMax(opts.PMTUDSizes)
+	}
+
+	start := time.Now()
+	res := pmtud.ProbeBisect(ctx, addr, floor, ceiling, urlForSize, client)
+	tr.Duration = time.Since(start)
+	tr.TimeMs = tr.Duration.Milliseconds()
+	tr.IP = &IpObservation{IP: addr.String(), Type: "ipv6"}
+	tr.DiscoveredMTU = res.DiscoveredMTU
+	tr.PMTUDMethod = res.Method
+	tr.PMTUDInferred = res.Inferred
+
+	switch res.Outcome {
+	case pmtud.OutcomeOK:
+		tr.Status = StatusOK
+	case pmtud.OutcomeBlackhole:
+		tr.Status = StatusTimeout
+		tr.Notes = "pmtud_blackhole"
+	case pmtud.OutcomeICMPFiltered:
+		tr.Status = StatusOK
+		tr.Notes = "pmtud_icmp_filtered"
+	case pmtud.OutcomeTimeout:
+		tr.Status = StatusTimeout
+	default:
+		tr.Status = StatusBad
+	}
+	return tr
+}
+
+// minMax returns the smallest and largest values in sizes.
+func minMax(sizes []int) (min, max int) {
+	min, max = sizes[0], sizes[0]
+	for _, s := range sizes[1:] {
+		if s < min {
+			min = s
+		}
+		if s > max {
+			max = s
+		}
+	}
+	return min, max
+}
+
+// runResolverProbe serves resolver_aaaa/resolver_doh6: it queries every
+// resolver in opts.ExtraResolvers directly (over whichever of
+// UDP/TCP/DoT/DoH that resolver's URI names) for a well-known dual-stack
+// name's AAAA record, retrying over TCP when a UDP answer truncates.
+// resolver_aaaa reports whether every resolver answered; resolver_doh6
+// reports whether any DoH resolver's own transport connection used IPv6.
+func (r *Runner) runResolverProbe(ctx context.Context, opts Options, tn TestName) TestResult {
+	if len(opts.ExtraResolvers) == 0 {
+		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no extra resolvers configured"}
+	}
+	d := strings.TrimSpace(opts.Domain)
+	if d == "" {
+		d = defaultDomain
+	}
+	qname := "ds." + d
+
+	var notes []string
+	allAAAA := true
+	anyV6DoH := false
+	for _, spec := range opts.ExtraResolvers {
+		resolver, err := dnsprobe.ParseResolver(spec)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s=parse_error(%v)", spec, err))
+			allAAAA = false
+			continue
+		}
+		if tn == TestResolverDoH6 && resolver.Scheme != "https" {
+			continue
+		}
+
+		res, err := dnsprobe.DoResolver(ctx, resolver, dnsprobe.Query{Name: qname, Qtype: dnsprobe.TypeAAAA})
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s=error(%v)", resolver, err))
+			allAAAA = false
+			continue
+		}
+		hasAAAA := len(res.Answers) > 0
+		entry := fmt.Sprintf("%s=%s,rtt=%s,via=%s", resolver, aaaaLabel(hasAAAA), res.RTT.Round(time.Millisecond), res.Via)
+		if res.Truncated {
+			entry += ",truncated"
+			if resolver.Scheme == "udp" {
+				tcpRes, tcpErr := dnsprobe.DoResolver(ctx, dnsprobe.Resolver{Scheme: "tcp", Target: resolver.Target}, dnsprobe.Query{Name: qname, Qtype: dnsprobe.TypeAAAA})
+				if tcpErr != nil || len(tcpRes.Answers) == 0 {
+					entry += ",no_tcp_fallback"
+				}
+			}
+		}
+		notes = append(notes, entry)
+		if !hasAAAA {
+			allAAAA = false
+		}
+		if resolver.Scheme == "https" && res.Via == "v6" {
+			anyV6DoH = true
+		}
+	}
+
+	tr := TestResult{Name: tn, URL: fmt.Sprintf("dns://%s?type=%d", qname, dnsprobe.TypeAAAA), Notes: strings.Join(notes, "; ")}
+	if tn == TestResolverDoH6 {
+		if anyV6DoH {
+			tr.Status = StatusOK
+		} else {
+			tr.Status = StatusBad
+		}
+		return tr
+	}
+	if allAAAA {
+		tr.Status = StatusOK
+	} else {
+		tr.Status = StatusBad
+	}
+	return tr
+}
+
+func aaaaLabel(hasAAAA bool) string {
+	if hasAAAA {
+		return "aaaa_ok"
+	}
+	return "no_aaaa"
+}
+
+// runNetcheckProbe serves netcheck_v4/netcheck_v6: it runs a STUN (RFC
+// 5389) binding exchange against opts.STUNServersV4/V6 via
+// pkg/ipv6test/netcheck to classify NAT behavior, hairpinning, CGNAT
+// (v4), and link-local/ULA-only IPv6 configurations, independent of DNS
+// and the HTTP runner.
+func (r *Runner) runNetcheckProbe(ctx context.Context, opts Options, tn TestName) TestResult {
+	family, famLabel, servers := "ip4", "ipv4", opts.STUNServersV4
+	if tn == TestNetcheckV6 {
+		family, famLabel, servers = "ip6", "ipv6", opts.STUNServersV6
+	}
+	if len(servers) == 0 {
+		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no STUN servers configured"}
+	}
+
+	start := time.Now()
+	rep := netcheck.Check(ctx, family, servers)
+	tr := TestResult{
+		Name:              tn,
+		Duration:          time.Since(start),
+		TimeMs:            time.Since(start).Milliseconds(),
+		NATType:           rep.NATType,
+		HairpinTested:     rep.HairpinTested,
+		HairpinWorks:      rep.HairpinWorks,
+		CGNATDetected:     rep.CGNAT,
+		IPv6LinkLocalOnly: rep.LinkLocalOnly,
+		IPv6ULAOnly:       rep.ULAOnly,
+	}
+	if rep.Mapped.IsValid() {
+		tr.IP = &IpObservation{IP: rep.Mapped.Addr().String(), Type: famLabel}
+	}
+	if rep.Err != "" {
+		tr.Status = StatusError
+		tr.Error = rep.Err
+		return tr
+	}
+	tr.Status = StatusOK
+	return tr
+}
+
+// runDNSProbe serves the dnsprobe-backed tests (dns_a, dns_aaaa, ...). It
+// tries the configured resolvers (falling back to the system resolver(s)
+// when none are configured) in order and reports the first one that
+// answers, mirroring the "one row per test" shape the HTTP runner produces.
+func (r *Runner) runDNSProbe(ctx context.Context, opts Options, tn TestName) TestResult {
+	qname, qtype, withEDNS0, dnssecOK := dnsQuerySpec(tn, opts.Domain)
+
+	resolvers := opts.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = dnsprobe.SystemResolvers()
+	}
+	if len(resolvers) == 0 {
+		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no resolvers configured and none discovered"}
+	}
+
+	bufSize := opts.EDNS0BufferSize
+	if bufSize == 0 {
+		bufSize = defaultEDNS0Buffer
+	}
+	if tn == TestDNSEDNS0 {
+		// Start at the large end so truncation can be detected; the
+		// fallback probe below retries at 1232 if this one truncates.
+		bufSize = 4096
+	}
+	query := dnsprobe.Query{Name: qname, Qtype: qtype}
+	if withEDNS0 {
+		query.EDNS0BufferSize = bufSize
+		query.DNSSECOK = dnssecOK
+	}
+
+	var best *dnsprobe.Result
+	var lastErr error
+	for _, resolver := range resolvers {
+		start := time.Now()
+		res, err := dnsprobe.Do(ctx, resolver, query)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		res.RTT = time.Since(start)
+		best = &res
+		break
+	}
+
+	tr := TestResult{Name: tn, URL: fmt.Sprintf("dns://%s?type=%d", qname, qtype)}
+	if best == nil {
+		tr.Status = StatusError
+		if lastErr != nil {
+			tr.Error = lastErr.Error()
+		} else {
+			tr.Error = "no response"
+		}
+		return tr
+	}
+
+	tr.TimeMs = best.RTT.Milliseconds()
+	tr.Duration = best.RTT
+	tr.DNSResponseCode = best.RCODE
+	tr.DNSFlags = best.Flags
+	tr.DNSAnswerBytes = best.AnswerBytes
+
+	switch {
+	case best.RCODE != 0:
+		tr.Status = StatusBad
+		tr.Notes = fmt.Sprintf("rcode=%d", best.RCODE)
+	case best.Truncated:
+		tr.Status = StatusBad
+		tr.Notes = "truncated"
+	case tr.Duration > opts.SlowThreshold:
+		tr.Status = StatusSlow
+	default:
+		tr.Status = StatusOK
+	}
+
+	if dnssecOK && best.RCODE == 0 && !best.Truncated && !best.HasRRSIG {
+		if tr.Notes != "" {
+			tr.Notes += "; "
+		}
+		tr.Notes += "no RRSIG in DNSSEC-OK response"
+	}
+	if len(best.Answers) > 0 {
+		tr.IP = &IpObservation{IP: best.Answers[0], Type: dnsAnswerType(qtype), Via: best.Via}
+	}
+
+	// The dns_edns0 test additionally probes a smaller buffer size: if a
+	// large (4096) query truncates but a 1232-byte one succeeds, that's a
+	// resolver-path PMTU/fragmentation problem rather than a DNS problem.
+	if tn == TestDNSEDNS0 && best.Truncated {
+		small := query
+		small.EDNS0BufferSize = 1232
+		for _, resolver := range resolvers {
+			if res, err := dnsprobe.Do(ctx, resolver, small); err == nil && !res.Truncated && res.RCODE == 0 {
+				if tr.Notes != "" {
+					tr.Notes += "; "
+				}
+				tr.Notes += "edns0_pmtu_fragmentation (4096 truncates, 1232 succeeds)"
+				break
+			}
+		}
+	}
+	return tr
+}
+
+// runPTRProbe serves rdns_v4/rdns_v6: it reverse-resolves the client's
+// observed address (from ipv4/ipv6, whichever earlier test populated it),
+// then forward-confirms the PTR name resolves back to the same address.
+func (r *Runner) runPTRProbe(ctx context.Context, opts Options, tn TestName, ipv4, ipv6 *IpObservation) TestResult {
+	obs := ipv4
+	if tn == TestRDNSv6 {
+		obs = ipv6
+	}
+	if obs == nil || obs.IP == "" {
+		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no observed address to reverse yet"}
+	}
+	addr, err := netip.ParseAddr(obs.IP)
+	if err != nil {
+		return TestResult{Name: tn, Status: StatusError, Error: err.Error()}
+	}
+
+	resolvers := opts.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = dnsprobe.SystemResolvers()
+	}
+	if len(resolvers) == 0 {
+		return TestResult{Name: tn, Status: StatusSkipped, Notes: "no resolvers configured and none discovered"}
+	}
+
+	qname := ptrQName(addr)
+	tr := TestResult{Name: tn, URL: fmt.Sprintf("dns://%s?type=%d", qname, dnsprobe.TypePTR)}
+
+	var ptr *dnsprobe.Result
+	var lastErr error
+	for _, resolver := range resolvers {
+		start := time.Now()
+		res, err := dnsprobe.Do(ctx, resolver, dnsprobe.Query{Name: qname, Qtype: dnsprobe.TypePTR})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		res.RTT = time.Since(start)
+		ptr = &res
+		break
+	}
+	if ptr == nil {
+		tr.Status = StatusError
+		if lastErr != nil {
+			tr.Error = lastErr.Error()
+		} else {
+			tr.Error = "no response"
+		}
+		return tr
+	}
+
+	tr.TimeMs = ptr.RTT.Milliseconds()
+	tr.Duration = ptr.RTT
+	tr.DNSResponseCode = ptr.RCODE
+	tr.DNSFlags = ptr.Flags
+
+	if ptr.RCODE != 0 || len(ptr.Answers) == 0 {
+		tr.Status = StatusBad
+		tr.Notes = "no PTR record"
+		return tr
+	}
+	tr.PTRName = ptr.Answers[0]
+
+	fwdType := dnsprobe.TypeA
+	if tn == TestRDNSv6 {
+		fwdType = dnsprobe.TypeAAAA
+	}
+	confirmed := false
+	for _, resolver := range resolvers {
+		fwd, err := dnsprobe.Do(ctx, resolver, dnsprobe.Query{Name: tr.PTRName, Qtype: fwdType})
+		if err != nil || fwd.RCODE != 0 {
+			continue
+		}
+		for _, a := range fwd.Answers {
+			if fa, err := netip.ParseAddr(a); err == nil && fa == addr {
+				confirmed = true
+				break
+			}
+		}
+		if confirmed {
+			break
+		}
+	}
+	tr.ForwardConfirmed = confirmed
+
+	switch {
+	case confirmed:
+		tr.Status = StatusOK
+	default:
+		tr.Status = StatusBad
+		tr.Notes = "forward confirmation failed"
+	}
+	if looksGeneric(tr.PTRName) {
+		if tr.Notes != "" {
+			tr.Notes += "; "
+		}
+		tr.Notes += "generic rDNS name"
+	}
+	return tr
+}
+
+// ptrQName synthesizes the in-addr.arpa/ip6.arpa QNAME for addr: IPv4
+// reverses the 4 octets, IPv6 reverses all 32 nibbles of the expanded form.
+func ptrQName(addr netip.Addr) string {
+	if addr.Is4() {
+		o := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", o[3], o[2], o[1], o[0])
+	}
+	b := addr.As16()
+	var nibbles []byte
+	for i := len(b) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, "0123456789abcdef"[b[i]&0x0f], "0123456789abcdef"[b[i]>>4])
+	}
+	var sb strings.Builder
+	for _, n := range nibbles {
+		sb.WriteByte(n)
+		sb.WriteByte('.')
+	}
+	sb.WriteString("ip6.arpa.")
+	return sb.String()
+}
+
+// looksGeneric flags PTR names that just encode the IP address itself
+// (e.g. "123-45-67-89.dyn.example.net" or a long hex run), which tells an
+// operator little beyond "this is dynamic/unassigned".
+func looksGeneric(name string) bool {
+	digits, hexRun := 0, 0
+	longestHexRun := 0
+	for _, c := range name {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+			hexRun++
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			hexRun++
+		default:
+			if hexRun > longestHexRun {
+				longestHexRun = hexRun
+			}
+			hexRun = 0
+		}
+	}
+	if hexRun > longestHexRun {
+		longestHexRun = hexRun
+	}
+	return digits >= 6 || longestHexRun >= 8
+}
+
+// dnsQuerySpec maps a dnsprobe TestName to the question it should ask.
+func dnsQuerySpec(tn TestName, domain string) (qname string, qtype dnsprobe.Type, edns0 bool, dnssecOK bool) {
+	d := strings.TrimSpace(domain)
+	if d == "" {
+		d = defaultDomain
+	}
+	switch tn {
+	case TestDNSA:
+		return "ipv4." + d, dnsprobe.TypeA, false, false
+	case TestDNSAAAA:
+		return "ipv6." + d, dnsprobe.TypeAAAA, false, false
+	case TestDNSEDNS0:
+		return "ds." + d, dnsprobe.TypeAAAA, true, false
+	case TestDNSDNSSECOk:
+		return d, dnsprobe.TypeAAAA, true, true
+	case TestDNSGlueV6:
+		return "mtu1280." + d, dnsprobe.TypeAAAA, false, false
+	default:
+		return d, dnsprobe.TypeA, false, false
+	}
+}
+
+func dnsAnswerType(qtype dnsprobe.Type) string {
+	if qtype == dnsprobe.TypeAAAA {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
 func parseIPObservation(body []byte) *IpObservation {
 	var ipObs IpObservation
 	if err := json.Unmarshal(body, &ipObs); err == nil {
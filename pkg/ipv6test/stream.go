@@ -0,0 +1,245 @@
+package ipv6test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// defaultMaxParallel is used when Options.MaxParallel is unset.
+const defaultMaxParallel = 4
+
+// defaultMaxConnsPerHost is used when Options.MaxConnsPerHost is unset.
+const defaultMaxConnsPerHost = 6
+
+// EventPhase is a lifecycle point reported for a single test while
+// RunStream runs it. HTTP-backed tests report dns/connected/tls as
+// net/http's own httptrace hooks fire; everything else only ever reports
+// started and done.
+type EventPhase string
+
+const (
+	PhaseStarted   EventPhase = "started"
+	PhaseDNS       EventPhase = "dns"
+	PhaseConnected EventPhase = "connected"
+	PhaseTLS       EventPhase = "tls"
+	PhaseDone      EventPhase = "done"
+)
+
+// Event is one progress notification emitted by RunStream.
+type Event struct {
+	TestName      TestName
+	Phase         EventPhase
+	Timestamp     time.Time
+	PartialResult *TestResult // set only when Phase is PhaseDone
+}
+
+// testDep is one prerequisite edge in the dependency DAG RunStream
+// schedules against.
+type testDep struct {
+	On TestName
+
+	// RequireSuccess skips tn outright, instead of running it, when On
+	// didn't complete with a good status (see isGood).
+	RequireSuccess bool
+}
+
+// testDeps declares dependency edges for the handful of tests where
+// running with no ordering at all would be wasteful or confusing: the
+// heavy dual-stack MTU probe shouldn't bother running if dual-stack
+// connectivity is already broken, and the ASN/PTR lookups want whatever
+// IP observation ipv4_dns/ipv6_dns/dual_stack already produced rather
+// than discovering their own independently of the address the rest of
+// the report is about.
+var testDeps = map[TestName][]testDep{
+	TestDualStackMTU: {{On: TestDualStack, RequireSuccess: true}},
+	TestASNLookupV4:  {{On: TestIPv4DNS}, {On: TestDualStack}},
+	TestASNLookupV6:  {{On: TestIPv6DNS}, {On: TestDualStack}},
+	TestRDNSv4:       {{On: TestIPv4DNS}, {On: TestDualStack}},
+	TestRDNSv6:       {{On: TestIPv6DNS}, {On: TestDualStack}},
+}
+
+// dependsOn returns the TestNames tn declares as prerequisites, for
+// Catalog()'s Definition.DependsOn.
+func dependsOn(tn TestName) []TestName {
+	deps := testDeps[tn]
+	if len(deps) == 0 {
+		return nil
+	}
+	out := make([]TestName, len(deps))
+	for i, d := range deps {
+		out[i] = d.On
+	}
+	return out
+}
+
+// RunStream is the concurrent, dependency-aware core Run is built on. It
+// schedules every requested test as soon as its testDeps prerequisites
+// have completed, runs at most Options.MaxParallel at once, and streams
+// an Event per test per lifecycle phase on the returned channel. Once
+// every test has completed (or been skipped for a failed prerequisite),
+// the single aggregate RunResult is sent on the result channel and both
+// channels are closed.
+func (r *Runner) RunStream(ctx context.Context, req RunRequest) (<-chan Event, <-chan RunResult) {
+	opts := r.mergeOptions(req)
+	client := r.clientFor(opts)
+
+	tests := req.Tests
+	if len(tests) == 0 {
+		tests = defaultTests
+	}
+
+	events := make(chan Event, 4*len(tests)+1)
+	results := make(chan RunResult, 1)
+
+	go func() {
+		defer close(events)
+		defer close(results)
+
+		start := time.Now()
+		result := RunResult{
+			RunID:           randomRunID(),
+			StartedAt:       start,
+			SlowThresholdMs: opts.SlowThreshold.Milliseconds(),
+			TimeoutMs:       opts.Timeout.Milliseconds(),
+			PacketSizeBytes: opts.PacketSize,
+			AddressPolicy:   string(opts.AddressPolicy),
+		}
+
+		maxParallel := opts.MaxParallel
+		if maxParallel <= 0 {
+			maxParallel = defaultMaxParallel
+		}
+		sem := make(chan struct{}, maxParallel)
+
+		scheduled := make(map[TestName]bool, len(tests))
+		doneCh := make(map[TestName]chan struct{}, len(tests))
+		for _, tn := range tests {
+			scheduled[tn] = true
+			doneCh[tn] = make(chan struct{})
+		}
+
+		var mu sync.Mutex
+		doneResults := make(map[TestName]TestResult, len(tests))
+
+		var wg sync.WaitGroup
+		for _, tn := range tests {
+			tn := tn
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(doneCh[tn])
+
+				for _, dep := range testDeps[tn] {
+					if !scheduled[dep.On] {
+						continue
+					}
+					select {
+					case <-doneCh[dep.On]:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				mu.Lock()
+				skip := ""
+				var ipv4, ipv6 *IpObservation
+				for _, dep := range testDeps[tn] {
+					depRes, ok := doneResults[dep.On]
+					if !ok {
+						continue
+					}
+					if dep.RequireSuccess && !isGood(depRes.Status) {
+						skip = "prerequisite test did not succeed"
+					}
+					if depRes.IP != nil {
+						switch depRes.IP.Type {
+						case "ipv4":
+							ipv4 = depRes.IP
+						case "ipv6":
+							ipv6 = depRes.IP
+						}
+					}
+				}
+				mu.Unlock()
+
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-sem }()
+
+				events <- Event{TestName: tn, Phase: PhaseStarted, Timestamp: time.Now()}
+
+				var tr TestResult
+				if skip != "" {
+					tr = TestResult{Name: tn, Status: StatusSkipped, Notes: skip}
+				} else {
+					tr = r.runDispatch(traceContext(ctx, tn, events), client, opts, tn, ipv4, ipv6)
+				}
+
+				mu.Lock()
+				doneResults[tn] = tr
+				mu.Unlock()
+
+				events <- Event{TestName: tn, Phase: PhaseDone, Timestamp: time.Now(), PartialResult: &tr}
+			}()
+		}
+		wg.Wait()
+
+		for _, tn := range tests {
+			tr := doneResults[tn]
+			result.Results = append(result.Results, tr)
+			if tr.IP != nil {
+				switch tr.IP.Type {
+				case "ipv4":
+					if result.IPv4 == nil {
+						result.IPv4 = tr.IP
+					}
+				case "ipv6":
+					if result.IPv6 == nil {
+						result.IPv6 = tr.IP
+					}
+				}
+			}
+		}
+		result.AddrSelection = r.computeAddrSelection(ctx, opts, result)
+		if opts.IPMeta != nil {
+			enrichIPMeta(ctx, opts.IPMeta, result.IPv4)
+			enrichIPMeta(ctx, opts.IPMeta, result.IPv6)
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		results <- result
+	}()
+
+	return events, results
+}
+
+// traceContext wraps ctx with an httptrace.ClientTrace that emits
+// dns/connected/tls Events for tn whenever the HTTP-backed tests make a
+// net/http request under it; tests that don't go through net/http (the
+// native DNS and STUN probes) simply never trigger these hooks.
+func traceContext(ctx context.Context, tn TestName, events chan<- Event) context.Context {
+	emit := func(phase EventPhase) {
+		select {
+		case events <- Event{TestName: tn, Phase: phase, Timestamp: time.Now()}:
+		default:
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { emit(PhaseDNS) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				emit(PhaseConnected)
+			}
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				emit(PhaseTLS)
+			}
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package ipv6test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDependsOn(t *testing.T) {
+	cases := []struct {
+		name TestName
+		want []TestName
+	}{
+		{TestDualStackMTU, []TestName{TestDualStack}},
+		{TestASNLookupV4, []TestName{TestIPv4DNS, TestDualStack}},
+		{TestASNLookupV6, []TestName{TestIPv6DNS, TestDualStack}},
+		{TestRDNSv4, []TestName{TestIPv4DNS, TestDualStack}},
+		{TestRDNSv6, []TestName{TestIPv6DNS, TestDualStack}},
+		{TestIPv4DNS, nil},
+	}
+	for _, c := range cases {
+		t.Run(string(c.name), func(t *testing.T) {
+			got := dependsOn(c.name)
+			if len(got) != len(c.want) {
+				t.Fatalf("dependsOn(%s) = %v, want %v", c.name, got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("dependsOn(%s) = %v, want %v", c.name, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// TestRunStreamSkipsOnFailedPrerequisite exercises RunStream's dependency
+// scheduling against real HTTP endpoints: TestDualStackMTU requires
+// TestDualStack to succeed (RequireSuccess: true in testDeps), so a failing
+// TestDualStack must make TestDualStackMTU skip rather than run its (much
+// heavier) PMTUD probe.
+func TestRunStreamSkipsOnFailedPrerequisite(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	opts := DefaultOptions()
+	opts.Timeout = 2 * time.Second
+	opts.Endpoints = map[TestName]string{TestDualStack: bad.URL}
+	runner := NewRunner(opts)
+
+	result, err := runner.Run(context.Background(), RunRequest{Tests: []TestName{TestDualStack, TestDualStackMTU}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	byName := make(map[TestName]TestResult, len(result.Results))
+	for _, tr := range result.Results {
+		byName[tr.Name] = tr
+	}
+
+	dsResult, ok := byName[TestDualStack]
+	if !ok || dsResult.Status != StatusBad {
+		t.Fatalf("dual_stack result = %+v, want StatusBad", dsResult)
+	}
+	mtuResult, ok := byName[TestDualStackMTU]
+	if !ok {
+		t.Fatal("dual_stack_mtu missing from results")
+	}
+	if mtuResult.Status != StatusSkipped {
+		t.Fatalf("dual_stack_mtu status = %s, want %s (its prerequisite failed)", mtuResult.Status, StatusSkipped)
+	}
+	if mtuResult.Notes == "" {
+		t.Fatal("dual_stack_mtu should note why it was skipped")
+	}
+}
+
+// TestRunStreamResultsPreserveRequestedOrder checks that RunStream's
+// concurrent scheduling still assembles RunResult.Results in the order the
+// caller asked for, even though the underlying goroutines may finish in a
+// different order.
+func TestRunStreamResultsPreserveRequestedOrder(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	opts := DefaultOptions()
+	opts.Timeout = 2 * time.Second
+	opts.Endpoints = map[TestName]string{
+		TestIPv4DNS: slow.URL,
+		TestIPv6DNS: fast.URL,
+	}
+	runner := NewRunner(opts)
+
+	result, err := runner.Run(context.Background(), RunRequest{Tests: []TestName{TestIPv4DNS, TestIPv6DNS}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(result.Results) != 2 || result.Results[0].Name != TestIPv4DNS || result.Results[1].Name != TestIPv6DNS {
+		t.Fatalf("Results = %+v, want [ipv4_dns, ipv6_dns] in that order despite ipv6_dns finishing first", result.Results)
+	}
+}
@@ -2,6 +2,8 @@ package ipv6test
 
 import (
 	"time"
+
+	"github.com/falling-sky/source/pkg/ipv6test/dialpolicy"
 )
 
 // TestName enumerates supported tests.
@@ -16,8 +18,46 @@ const (
 	TestDNSV6Resolver TestName = "dns_v6_resolver" // resolver can reach IPv6-only auth
 	TestASNLookupV4   TestName = "asn_v4"          // ASN lookup over IPv4
 	TestASNLookupV6   TestName = "asn_v6"          // ASN lookup over IPv6
+
+	TestDNSA        TestName = "dns_a"         // plain A query
+	TestDNSAAAA     TestName = "dns_aaaa"      // plain AAAA query
+	TestDNSEDNS0    TestName = "dns_edns0"     // AAAA query with an EDNS0 OPT RR
+	TestDNSDNSSECOk TestName = "dns_dnssec_ok" // query with the DNSSEC-OK bit set
+	TestDNSGlueV6   TestName = "dns_glue_v6"   // AAAA query against a delegation with no glue
+
+	TestRDNSv4 TestName = "rdns_v4" // PTR lookup + forward-confirm of the observed IPv4 address
+	TestRDNSv6 TestName = "rdns_v6" // PTR lookup + forward-confirm of the observed IPv6 address
+
+	// TestResolverAAAA and TestResolverDoH6 probe Options.ExtraResolvers
+	// directly (see Runner.runResolverProbe), independent of both the
+	// HTTP runner and the system-resolver dnsprobe tests above.
+	TestResolverAAAA TestName = "resolver_aaaa" // every configured resolver returns AAAA for a dual-stack name
+	TestResolverDoH6 TestName = "resolver_doh6" // at least one configured DoH resolver is itself reachable over IPv6
+
+	// TestNetcheckV4 and TestNetcheckV6 probe Options.STUNServersV4/V6
+	// directly via pkg/ipv6test/netcheck (see Runner.runNetcheckProbe),
+	// independent of DNS and the HTTP runner.
+	TestNetcheckV4 TestName = "netcheck_v4" // STUN-based NAT/CGNAT classification over IPv4
+	TestNetcheckV6 TestName = "netcheck_v6" // STUN-based NAT/hairpin/link-local-only classification over IPv6
 )
 
+// dnsTests is the set of TestNames handled by the dnsprobe subsystem rather
+// than the HTTP runner.
+var dnsTests = map[TestName]bool{
+	TestDNSA:        true,
+	TestDNSAAAA:     true,
+	TestDNSEDNS0:    true,
+	TestDNSDNSSECOk: true,
+	TestDNSGlueV6:   true,
+	TestRDNSv4:      true,
+	TestRDNSv6:      true,
+}
+
+// IsDNSTest reports whether tn is served by the native DNS probe subsystem.
+func IsDNSTest(tn TestName) bool {
+	return dnsTests[tn]
+}
+
 // Status values mirror the OpenAPI spec.
 type Status string
 
@@ -39,6 +79,11 @@ type Definition struct {
 	LargePayload bool
 	ExampleURL   string
 	PacketSize   int
+
+	// DependsOn lists tests RunStream's scheduler waits on before starting
+	// this one (see testDeps in stream.go). Nil for tests with no
+	// prerequisites.
+	DependsOn []TestName
 }
 
 // IpObservation is what we can infer from the target response.
@@ -49,6 +94,14 @@ type IpObservation struct {
 	Via     string `json:"via,omitempty"`
 	ASN     int    `json:"asn,omitempty"`
 	ASNName string `json:"asn_name,omitempty"`
+
+	// Fields below are populated by the ipmeta provider (see
+	// pkg/ipv6test/ipmeta), when one is configured on Options.
+	Country           string `json:"country,omitempty"`
+	RegisteredCountry string `json:"registeredCountry,omitempty"`
+	IsAnycast         bool   `json:"isAnycast,omitempty"`
+	IsULA             bool   `json:"isUla,omitempty"`
+	IsLinkLocal       bool   `json:"isLinkLocal,omitempty"`
 }
 
 // TestResult is the outcome of a single probe.
@@ -63,6 +116,38 @@ type TestResult struct {
 	HTTPStatusCode int            `json:"httpStatusCode,omitempty"`
 	Error          string         `json:"error,omitempty"`
 	Duration       time.Duration  `json:"-"`
+
+	// DNS-specific fields, populated only for dnsprobe-backed tests (see
+	// IsDNSTest).
+	DNSResponseCode int    `json:"dnsResponseCode,omitempty"`
+	DNSFlags        uint16 `json:"dnsFlags,omitempty"`
+	DNSAnswerBytes  int    `json:"dnsAnswerBytes,omitempty"`
+
+	// PTR-specific fields, populated only for rdns_v4/rdns_v6.
+	PTRName          string `json:"ptrName,omitempty"`
+	ForwardConfirmed bool   `json:"forwardConfirmed,omitempty"`
+
+	// PMTUD-specific fields, populated for ipv6_mtu/dual_stack_mtu (see
+	// pkg/ipv6test/pmtud).
+	DiscoveredMTU int    `json:"discoveredMtu,omitempty"`
+	PMTUDMethod   string `json:"pmtudMethod,omitempty"`
+	PMTUDInferred bool   `json:"pmtudInferred,omitempty"`
+
+	// Dial-policy fields, populated for HTTP-backed tests whenever
+	// Options.AddressPolicy is set (see pkg/ipv6test/dialpolicy).
+	FamilyUsed      string `json:"familyUsed,omitempty"`
+	CandidatesTried int    `json:"candidatesTried,omitempty"`
+	Fallback        bool   `json:"fallback,omitempty"`
+	FilteredFamily  string `json:"filteredFamily,omitempty"`
+
+	// Netcheck-specific fields, populated only for netcheck_v4/netcheck_v6
+	// (see pkg/ipv6test/netcheck).
+	NATType           string `json:"natType,omitempty"`
+	HairpinTested     bool   `json:"hairpinTested,omitempty"`
+	HairpinWorks      bool   `json:"hairpinWorks,omitempty"`
+	CGNATDetected     bool   `json:"cgnatDetected,omitempty"`
+	IPv6LinkLocalOnly bool   `json:"ipv6LinkLocalOnly,omitempty"`
+	IPv6ULAOnly       bool   `json:"ipv6UlaOnly,omitempty"`
 }
 
 // RunRequest configures a run.
@@ -71,17 +156,34 @@ type RunRequest struct {
 	Timeout         time.Duration
 	SlowThreshold   time.Duration
 	PacketSizeBytes int
+
+	// AddressPolicy overrides Options.AddressPolicy for this run, when set.
+	AddressPolicy dialpolicy.Policy
+}
+
+// AddrSelectionResult is the RFC 6724 destination-address-selection
+// prediction for the dual-stack test endpoint: which family the client's
+// OS would pick given its observed source addresses, compared against
+// which family the dual-stack HTTP probe actually used.
+type AddrSelectionResult struct {
+	PredictedFamily string `json:"predictedFamily"` // "ipv4" or "ipv6"
+	Rule            string `json:"rule"`            // deciding RFC 6724 rule
+	LabelMismatch   bool   `json:"labelMismatch"`
+	ActualFamily    string `json:"actualFamily,omitempty"`
+	Matches         bool   `json:"matches"`
 }
 
 // RunResult is the aggregate outcome.
 type RunResult struct {
-	RunID           string         `json:"runId"`
-	StartedAt       time.Time      `json:"startedAt"`
-	DurationMs      int64          `json:"durationMs"`
-	IPv4            *IpObservation `json:"ipv4,omitempty"`
-	IPv6            *IpObservation `json:"ipv6,omitempty"`
-	Results         []TestResult   `json:"results"`
-	SlowThresholdMs int64          `json:"slowThresholdMs"`
-	TimeoutMs       int64          `json:"timeoutMs"`
-	PacketSizeBytes int            `json:"packetSizeBytes"`
+	RunID           string               `json:"runId"`
+	StartedAt       time.Time            `json:"startedAt"`
+	DurationMs      int64                `json:"durationMs"`
+	IPv4            *IpObservation       `json:"ipv4,omitempty"`
+	IPv6            *IpObservation       `json:"ipv6,omitempty"`
+	Results         []TestResult         `json:"results"`
+	SlowThresholdMs int64                `json:"slowThresholdMs"`
+	TimeoutMs       int64                `json:"timeoutMs"`
+	PacketSizeBytes int                  `json:"packetSizeBytes"`
+	AddrSelection   *AddrSelectionResult `json:"addrSelection,omitempty"`
+	AddressPolicy   string               `json:"addressPolicy,omitempty"`
 }